@@ -0,0 +1,89 @@
+package bitset
+
+import "math/bits"
+
+// The functions below combine any number of sets in a single pass over the
+// words, following the same size rules as Union and Intersection: the
+// result has the size of the largest set.
+
+// Or returns a new set with the bits set in any of the sets.
+func Or(sets ...*Set) *Set {
+	res := New(maxLength(sets))
+	for w := range res.data {
+		var word uint64
+		for _, s := range sets {
+			if w < len(s.data) {
+				word |= s.data[w]
+			}
+		}
+		res.data[w] = word
+	}
+	return res
+}
+
+// OrCount returns the number of bits set in Or(sets...).
+func OrCount(sets ...*Set) int {
+	var count int
+	for w, n := 0, wordsNeeded(maxLength(sets)); w < n; w++ {
+		var word uint64
+		for _, s := range sets {
+			if w < len(s.data) {
+				word |= s.data[w]
+			}
+		}
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// And returns a new set with the bits set in all of the sets.
+func And(sets ...*Set) *Set {
+	res := New(maxLength(sets))
+	for w, n := 0, minWords(sets); w < n; w++ {
+		res.data[w] = andWord(sets, w)
+	}
+	return res
+}
+
+// AndCount returns the number of bits set in And(sets...).
+func AndCount(sets ...*Set) int {
+	var count int
+	for w, n := 0, minWords(sets); w < n; w++ {
+		count += bits.OnesCount64(andWord(sets, w))
+	}
+	return count
+}
+
+// andWord returns the intersection of the w-th word of all sets, stopping
+// as soon as it becomes zero.
+func andWord(sets []*Set, w int) uint64 {
+	word := sets[0].data[w]
+	for _, s := range sets[1:] {
+		if word == 0 {
+			break
+		}
+		word &= s.data[w]
+	}
+	return word
+}
+
+func maxLength(sets []*Set) int {
+	var n int
+	for _, s := range sets {
+		n = max(n, s.length)
+	}
+	return n
+}
+
+// minWords returns the number of words in the smallest set.
+func minWords(sets []*Set) int {
+	if len(sets) == 0 {
+		return 0
+	}
+
+	n := len(sets[0].data)
+	for _, s := range sets[1:] {
+		n = min(n, len(s.data))
+	}
+	return n
+}
@@ -0,0 +1,79 @@
+package bitset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomSets(rnd *rand.Rand, k int) []*Set {
+	sets := make([]*Set, k)
+	for i := range sets {
+		sets[i] = New(1000 + rnd.Intn(200))
+		for j := 0; j < sets[i].Size(); j++ {
+			if rnd.Intn(4) != 0 {
+				sets[i].Set(j)
+			}
+		}
+	}
+	return sets
+}
+
+func TestOr(t *testing.T) {
+	sets := randomSets(rand.New(rand.NewSource(1)), 5)
+
+	want := sets[0]
+	for _, s := range sets[1:] {
+		want = want.Union(s)
+	}
+
+	if got := Or(sets...); !got.Equal(want) {
+		t.Errorf("Or() = %s; want = %s", got.Ranges(), want.Ranges())
+	}
+	if got := OrCount(sets...); got != want.Count() {
+		t.Errorf("OrCount() = %d; want = %d", got, want.Count())
+	}
+}
+
+func TestAnd(t *testing.T) {
+	sets := randomSets(rand.New(rand.NewSource(2)), 5)
+
+	want := sets[0]
+	for _, s := range sets[1:] {
+		want = want.Intersection(s)
+	}
+
+	if got := And(sets...); !got.Equal(want) {
+		t.Errorf("And() = %s; want = %s", got.Ranges(), want.Ranges())
+	}
+	if got := AndCount(sets...); got != want.Count() {
+		t.Errorf("AndCount() = %d; want = %d", got, want.Count())
+	}
+}
+
+func TestAggregateEmpty(t *testing.T) {
+	if Or().Size() != 0 || And().Size() != 0 {
+		t.Error("aggregating no sets should return an empty set")
+	}
+	if OrCount() != 0 || AndCount() != 0 {
+		t.Error("aggregating no sets should count zero bits")
+	}
+}
+
+func BenchmarkAnd(b *testing.B) {
+	sets := randomSets(rand.New(rand.NewSource(3)), 32)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		And(sets...)
+	}
+}
+
+func BenchmarkAndPairwise(b *testing.B) {
+	sets := randomSets(rand.New(rand.NewSource(3)), 32)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		res := sets[0]
+		for _, s := range sets[1:] {
+			res = res.Intersection(s)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package bitset
+
+import "math/bits"
+
+// The set operations below accept sets of different sizes. A set is treated
+// as if it was padded with zeroes up to the size of the other set. The
+// in-place variants keep the size of the receiver and ignore any bits in
+// other beyond it, unless the receiver is growable in which case it grows to
+// the size of other. The allocating variants return a set with the size of
+// the larger of the two.
+
+// InPlaceUnion sets all bits in s that are set in other.
+func (s *Set) InPlaceUnion(other *Set) {
+	s.fit(other.length)
+	s.modified(0, len(s.data))
+	n := min(len(s.data), len(other.data))
+	for i := 0; i < n; i++ {
+		s.data[i] |= other.data[i]
+	}
+	s.clearPadding()
+}
+
+// InPlaceIntersection clears all bits in s that are not set in other.
+func (s *Set) InPlaceIntersection(other *Set) {
+	s.modified(0, len(s.data))
+	n := min(len(s.data), len(other.data))
+	for i := 0; i < n; i++ {
+		s.data[i] &= other.data[i]
+	}
+	clear(s.data[n:])
+}
+
+// InPlaceDifference clears all bits in s that are set in other.
+func (s *Set) InPlaceDifference(other *Set) {
+	s.modified(0, len(s.data))
+	n := min(len(s.data), len(other.data))
+	for i := 0; i < n; i++ {
+		s.data[i] &^= other.data[i]
+	}
+}
+
+// InPlaceSymmetricDifference flips all bits in s that are set in other.
+func (s *Set) InPlaceSymmetricDifference(other *Set) {
+	s.fit(other.length)
+	s.modified(0, len(s.data))
+	n := min(len(s.data), len(other.data))
+	for i := 0; i < n; i++ {
+		s.data[i] ^= other.data[i]
+	}
+	s.clearPadding()
+}
+
+// Union returns a new set with the bits set in either s or other.
+func (s *Set) Union(other *Set) *Set {
+	r := s.cloneSize(max(s.length, other.length))
+	r.InPlaceUnion(other)
+	return r
+}
+
+// Intersection returns a new set with the bits set in both s and other.
+func (s *Set) Intersection(other *Set) *Set {
+	r := s.cloneSize(max(s.length, other.length))
+	r.InPlaceIntersection(other)
+	return r
+}
+
+// Difference returns a new set with the bits set in s but not in other.
+func (s *Set) Difference(other *Set) *Set {
+	r := s.cloneSize(max(s.length, other.length))
+	r.InPlaceDifference(other)
+	return r
+}
+
+// SymmetricDifference returns a new set with the bits set in exactly one of
+// s and other.
+func (s *Set) SymmetricDifference(other *Set) *Set {
+	r := s.cloneSize(max(s.length, other.length))
+	r.InPlaceSymmetricDifference(other)
+	return r
+}
+
+// UnionCardinality returns the number of bits set in s.Union(other).
+func (s *Set) UnionCardinality(other *Set) int {
+	a, b := s.data, other.data
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+
+	var count int
+	for i, w := range b {
+		count += bits.OnesCount64(a[i] | w)
+	}
+	for _, w := range a[len(b):] {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// IntersectionCardinality returns the number of bits set in
+// s.Intersection(other).
+func (s *Set) IntersectionCardinality(other *Set) int {
+	n := min(len(s.data), len(other.data))
+
+	var count int
+	for i := 0; i < n; i++ {
+		count += bits.OnesCount64(s.data[i] & other.data[i])
+	}
+	return count
+}
+
+// DifferenceCardinality returns the number of bits set in
+// s.Difference(other).
+func (s *Set) DifferenceCardinality(other *Set) int {
+	n := min(len(s.data), len(other.data))
+
+	var count int
+	for i := 0; i < n; i++ {
+		count += bits.OnesCount64(s.data[i] &^ other.data[i])
+	}
+	for _, w := range s.data[n:] {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// SymmetricDifferenceCardinality returns the number of bits set in
+// s.SymmetricDifference(other).
+func (s *Set) SymmetricDifferenceCardinality(other *Set) int {
+	a, b := s.data, other.data
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+
+	var count int
+	for i, w := range b {
+		count += bits.OnesCount64(a[i] ^ w)
+	}
+	for _, w := range a[len(b):] {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
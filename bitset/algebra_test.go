@@ -0,0 +1,98 @@
+package bitset
+
+import "testing"
+
+func newSetWith(n int, idx ...int) *Set {
+	s := New(n)
+	for _, i := range idx {
+		s.Set(i)
+	}
+	return s
+}
+
+func indices(s *Set) []int {
+	var res []int
+	for i := 0; i < s.Size(); i++ {
+		if s.Get(i) {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSetAlgebra(t *testing.T) {
+	a := newSetWith(128, 1, 2, 64, 100)
+	b := newSetWith(200, 2, 64, 65, 150)
+
+	for _, tt := range []struct {
+		name  string
+		got   *Set
+		count int
+		want  []int
+	}{
+		{"Union", a.Union(b), a.UnionCardinality(b), []int{1, 2, 64, 65, 100, 150}},
+		{"Intersection", a.Intersection(b), a.IntersectionCardinality(b), []int{2, 64}},
+		{"Difference", a.Difference(b), a.DifferenceCardinality(b), []int{1, 100}},
+		{"SymmetricDifference", a.SymmetricDifference(b), a.SymmetricDifferenceCardinality(b), []int{1, 65, 100, 150}},
+	} {
+		if tt.got.Size() != 200 {
+			t.Errorf("%s: Size() = %d; want = %d", tt.name, tt.got.Size(), 200)
+		}
+		if got := indices(tt.got); !equalInts(got, tt.want) {
+			t.Errorf("%s: got = %v; want = %v", tt.name, got, tt.want)
+		}
+		if tt.count != len(tt.want) {
+			t.Errorf("%s: cardinality = %d; want = %d", tt.name, tt.count, len(tt.want))
+		}
+	}
+
+	if got := indices(a); !equalInts(got, []int{1, 2, 64, 100}) {
+		t.Errorf("receiver was modified: %v", got)
+	}
+}
+
+func TestSetAlgebraInPlace(t *testing.T) {
+	b := newSetWith(200, 2, 64, 65, 150)
+
+	a := newSetWith(72, 1, 2, 64)
+	a.InPlaceUnion(b)
+	if got, want := indices(a), []int{1, 2, 64, 65}; !equalInts(got, want) {
+		t.Errorf("InPlaceUnion: got = %v; want = %v", got, want)
+	}
+	if a.Size() != 72 {
+		t.Errorf("a.Size() = %d; want = %d", a.Size(), 72)
+	}
+
+	a = newSetWith(72, 1, 2, 64)
+	a.InPlaceIntersection(b)
+	if got, want := indices(a), []int{2, 64}; !equalInts(got, want) {
+		t.Errorf("InPlaceIntersection: got = %v; want = %v", got, want)
+	}
+
+	a = newSetWith(72, 1, 2, 64)
+	a.InPlaceDifference(b)
+	if got, want := indices(a), []int{1}; !equalInts(got, want) {
+		t.Errorf("InPlaceDifference: got = %v; want = %v", got, want)
+	}
+
+	a = newSetWith(72, 1, 2, 64)
+	a.InPlaceSymmetricDifference(b)
+	if got, want := indices(a), []int{1, 65}; !equalInts(got, want) {
+		t.Errorf("InPlaceSymmetricDifference: got = %v; want = %v", got, want)
+	}
+	if a.Count() != 2 {
+		t.Errorf("a.Count() = %d; want = %d", a.Count(), 2)
+	}
+}
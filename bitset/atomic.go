@@ -0,0 +1,200 @@
+package bitset
+
+import (
+	"math/bits"
+	"runtime"
+	"sync/atomic"
+)
+
+const (
+	// atomicStripes is the largest number of counter pairs that the
+	// mutations of an AtomicSet are spread over, so that writers to
+	// different parts of the set don't contend on one cache line.
+	atomicStripes = 64
+
+	// stripeWords is the number of consecutive words that share a stripe.
+	stripeWords = 8
+
+	// snapshotAttempts is the number of times Snapshot tries to copy the
+	// set while writers keep going, before it holds them off.
+	snapshotAttempts = 4
+)
+
+// AtomicSet is a fixed size bitset that is safe for concurrent use. Bits are
+// updated with compare-and-swap on the word that holds them, so no locks are
+// taken.
+type AtomicSet struct {
+	data   []atomic.Uint64
+	length int
+
+	// stripes count the mutations that have started and finished, which
+	// lets Snapshot detect concurrent writers. paused is non-zero while a
+	// snapshot holds off new mutations.
+	stripes []stripe
+	paused  atomic.Int32
+}
+
+// stripe counts the mutations of the words that map to it. It is padded to
+// fill a cache line.
+type stripe struct {
+	started  atomic.Uint64
+	finished atomic.Uint64
+	_        [48]byte
+}
+
+// NewAtomic returns a new concurrent bitset with a given size.
+func NewAtomic(n int) *AtomicSet {
+	words := wordsNeeded(n)
+	return &AtomicSet{
+		data:    make([]atomic.Uint64, words),
+		length:  n,
+		stripes: make([]stripe, max(1, min(atomicStripes, (words+stripeWords-1)/stripeWords))),
+	}
+}
+
+// Set sets a bit to 1.
+func (s *AtomicSet) Set(i int) error {
+	_, err := s.TestAndSet(i)
+	return err
+}
+
+// TestAndSet sets a bit to 1 and returns whether it was already set. When
+// several goroutines race to set the same bit, exactly one of them sees
+// false.
+func (s *AtomicSet) TestAndSet(i int) (bool, error) {
+	if i < 0 || i >= s.length {
+		return false, errOutOfRange
+	}
+
+	w := &s.data[i>>log2Word]
+	mask := uint64(1) << uint(i&(wordSize-1))
+
+	if w.Load()&mask != 0 {
+		return true, nil
+	}
+
+	st := s.begin(i >> log2Word)
+	defer st.finished.Add(1)
+
+	for {
+		old := w.Load()
+		if old&mask != 0 {
+			return true, nil
+		}
+		if w.CompareAndSwap(old, old|mask) {
+			return false, nil
+		}
+	}
+}
+
+// Clear sets a bit to 0.
+func (s *AtomicSet) Clear(i int) error {
+	if i < 0 || i >= s.length {
+		return errOutOfRange
+	}
+
+	w := &s.data[i>>log2Word]
+	mask := uint64(1) << uint(i&(wordSize-1))
+
+	if w.Load()&mask == 0 {
+		return nil
+	}
+
+	st := s.begin(i >> log2Word)
+	defer st.finished.Add(1)
+
+	for {
+		old := w.Load()
+		if old&mask == 0 || w.CompareAndSwap(old, old&^mask) {
+			return nil
+		}
+	}
+}
+
+// Get returns whether a bit is set or not.
+func (s *AtomicSet) Get(i int) bool {
+	if i < 0 || i >= s.length {
+		return false
+	}
+	return s.data[i>>log2Word].Load()&(1<<uint(i&(wordSize-1))) != 0
+}
+
+// Size returns the number of bits, both ones and zeroes.
+func (s *AtomicSet) Size() int {
+	return s.length
+}
+
+// Count returns the number of bits set to one. Bits that are changed while
+// counting may or may not be included.
+func (s *AtomicSet) Count() int {
+	var count int
+	for i := range s.data {
+		count += bits.OnesCount64(s.data[i].Load())
+	}
+	return count
+}
+
+// Snapshot returns a copy of the set as it was at a single point in time.
+// The copy is retried while mutations overlap with it. If writes keep
+// overlapping, Snapshot holds off new ones until the mutations in flight
+// are done and it has its copy, so it never waits for writers to pause.
+func (s *AtomicSet) Snapshot() *Set {
+	res := New(s.length)
+	started := make([]uint64, len(s.stripes))
+
+	for attempt := 1; ; attempt++ {
+		if attempt == snapshotAttempts {
+			s.paused.Add(1)
+			defer s.paused.Add(-1)
+		}
+		if s.tryCopy(res.data, started) {
+			return res
+		}
+		runtime.Gosched()
+	}
+}
+
+// tryCopy copies the words of the set into data, and reports whether no
+// mutation overlapped with the copy.
+func (s *AtomicSet) tryCopy(data, started []uint64) bool {
+	// Reading finished before started makes sure that no mutation of
+	// the stripe was in flight once started was read.
+	for i := range s.stripes {
+		finished := s.stripes[i].finished.Load()
+		started[i] = s.stripes[i].started.Load()
+		if finished != started[i] {
+			return false
+		}
+	}
+
+	for i := range s.data {
+		data[i] = s.data[i].Load()
+	}
+
+	for i := range s.stripes {
+		if s.stripes[i].started.Load() != started[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// begin records that a mutation of the w-th word starts, first waiting for
+// any snapshot that holds off mutations. The mutation must add to finished
+// of the returned stripe when done.
+func (s *AtomicSet) begin(w int) *stripe {
+	st := &s.stripes[w/stripeWords%len(s.stripes)]
+	for {
+		for s.paused.Load() != 0 {
+			runtime.Gosched()
+		}
+
+		st.started.Add(1)
+		if s.paused.Load() == 0 {
+			return st
+		}
+
+		// A snapshot started holding off mutations in the meantime.
+		st.finished.Add(1)
+	}
+}
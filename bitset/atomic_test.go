@@ -0,0 +1,153 @@
+package bitset
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAtomicSet(t *testing.T) {
+	s := NewAtomic(1000)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < s.Size(); i += 8 {
+				s.Set(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if s.Count() != 1000 {
+		t.Fatalf("s.Count() = %d; want = %d", s.Count(), 1000)
+	}
+
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < s.Size(); i += 4 {
+				if i%3 == 0 {
+					s.Clear(i)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for i := 0; i < s.Size(); i++ {
+		if s.Get(i) != (i%3 != 0) {
+			t.Fatalf("s.Get(%d) = %v; want = %v", i, s.Get(i), i%3 != 0)
+		}
+	}
+}
+
+func TestAtomicSetTestAndSet(t *testing.T) {
+	const n = 256
+	s := NewAtomic(n)
+
+	var claimed [n]atomic.Int32
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				wasSet, err := s.TestAndSet(i)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !wasSet {
+					claimed[i].Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range claimed {
+		if c := claimed[i].Load(); c != 1 {
+			t.Errorf("bit %d was claimed %d times", i, c)
+		}
+	}
+}
+
+func TestAtomicSetSnapshot(t *testing.T) {
+	s := NewAtomic(4096)
+
+	// The writer sets bits in increasing order, so a consistent snapshot
+	// always holds a prefix of the set.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < s.Size(); i++ {
+			s.Set(i)
+		}
+	}()
+
+	for {
+		snap := s.Snapshot()
+		if next, ok := snap.NextClear(0); ok && next != snap.Count() {
+			t.Fatalf("inconsistent snapshot: first clear bit %d with %d bits set", next, snap.Count())
+		}
+		select {
+		case <-done:
+			if c := s.Snapshot().Count(); c != s.Size() {
+				t.Fatalf("snapshot.Count() = %d; want = %d", c, s.Size())
+			}
+			return
+		default:
+		}
+	}
+}
+
+func TestAtomicSetSnapshotBusy(t *testing.T) {
+	s := NewAtomic(1 << 20)
+
+	// Writers that never pause must not starve Snapshot.
+	stop := make(chan struct{})
+	var wg, running sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		running.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			running.Done()
+			for i := w; ; i = (i + 4) % s.Size() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				s.Set(i)
+				s.Clear(i)
+			}
+		}(w)
+	}
+
+	running.Wait()
+	for i := 0; i < 20; i++ {
+		s.Snapshot()
+	}
+
+	close(stop)
+	wg.Wait()
+}
+
+func TestAtomicSetOutOfRange(t *testing.T) {
+	s := NewAtomic(10)
+
+	if err := s.Set(10); err != errOutOfRange {
+		t.Errorf("s.Set() = %v; want = %v", err, errOutOfRange)
+	}
+	if _, err := s.TestAndSet(-1); err != errOutOfRange {
+		t.Errorf("s.TestAndSet() = %v; want = %v", err, errOutOfRange)
+	}
+	if err := s.Clear(10); err != errOutOfRange {
+		t.Errorf("s.Clear() = %v; want = %v", err, errOutOfRange)
+	}
+}
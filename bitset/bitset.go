@@ -2,65 +2,129 @@ package bitset
 
 import (
 	"errors"
+	"math/bits"
 	"strings"
 )
 
 var errOutOfRange = errors.New("index out of range")
 
+const (
+	wordSize = 64
+	log2Word = 6
+	allOnes  = ^uint64(0)
+)
+
 // Set ...
 type Set struct {
-	data []byte
+	data   []uint64
+	length int
+
+	// growable sets are extended rather than returning errOutOfRange when
+	// a bit beyond the end is set.
+	growable bool
+
+	// index is the optional rank and select index, dropped whenever the set
+	// is modified.
+	index *rankIndex
+
+	// cow tracks the chunks shared with snapshots of the set.
+	cow *cowState
 }
 
 // Set sets a bit to 1.
 func (s *Set) Set(i int) error {
-	if i < 0 || i >= s.Size() {
+	if i < 0 || !s.fit(i+1) {
 		return errOutOfRange
 	}
 
-	idx := i / 8
-	s.data[idx] = s.data[idx] | (1 << uint(i%8))
+	s.modified(i>>log2Word, i>>log2Word+1)
+	s.data[i>>log2Word] |= 1 << uint(i&(wordSize-1))
 
 	return nil
 }
 
-// Get returns whether a bit is set or not.
+// Clear sets a bit to 0.
+func (s *Set) Clear(i int) error {
+	if i < 0 {
+		return errOutOfRange
+	}
+	if i >= s.Size() {
+		// Bits beyond the end of a growable set are already zero.
+		if s.growable {
+			return nil
+		}
+		return errOutOfRange
+	}
+
+	s.modified(i>>log2Word, i>>log2Word+1)
+	s.data[i>>log2Word] &^= 1 << uint(i&(wordSize-1))
+
+	return nil
+}
+
+// Flip toggles a bit.
+func (s *Set) Flip(i int) error {
+	if i < 0 || !s.fit(i+1) {
+		return errOutOfRange
+	}
+
+	s.modified(i>>log2Word, i>>log2Word+1)
+	s.data[i>>log2Word] ^= 1 << uint(i&(wordSize-1))
+
+	return nil
+}
+
+// SetTo sets a bit to 1 if value is true, otherwise to 0.
+func (s *Set) SetTo(i int, value bool) error {
+	if value {
+		return s.Set(i)
+	}
+	return s.Clear(i)
+}
+
+// Get returns whether a bit is set or not. Bits outside of the set are
+// reported as not set.
 func (s *Set) Get(i int) bool {
-	return (s.data[i/8] & (1 << uint(i%8))) != 0
+	if i < 0 || i >= s.Size() {
+		return false
+	}
+	return (s.data[i>>log2Word] & (1 << uint(i&(wordSize-1)))) != 0
 }
 
 // Size returns the number of bits, both ones and zeroes.
 func (s *Set) Size() int {
-	return len(s.data) * 8
+	return s.length
 }
 
 // Count returns the number of bits set to one.
 func (s *Set) Count() int {
 	var count int
-	for _, b := range s.data {
-		for b > 0 {
-			count = count + int(b&1)
-			b >>= 1
-		}
+	for _, w := range s.data {
+		count += bits.OnesCount64(w)
 	}
 	return count
 }
 
 // All tests whether all bits are set.
 func (s *Set) All() bool {
-	for _, b := range s.data {
-		if (b ^ 0xff) > 0 {
+	n := s.length >> log2Word
+	for _, w := range s.data[:n] {
+		if w != allOnes {
 			return false
 		}
 	}
 
+	if mask := s.lastWordMask(); mask != allOnes {
+		return s.data[n]&mask == mask
+	}
+
 	return true
 }
 
 // Any tests whether any bit is set.
 func (s *Set) Any() bool {
-	for _, b := range s.data {
-		if (b | 0x00) > 0 {
+	for _, w := range s.data {
+		if w != 0 {
 			return true
 		}
 	}
@@ -69,39 +133,147 @@ func (s *Set) Any() bool {
 
 // None tests if no bits are set.
 func (s *Set) None() bool {
-	for _, b := range s.data {
-		if (b | 0x00) > 0 {
-			return false
+	return !s.Any()
+}
+
+// String returns the bits of the set as a string of ones and zeroes, starting
+// with bit 0.
+func (s *Set) String() string {
+	var b strings.Builder
+	b.Grow(s.Size())
+	for i := 0; i < s.Size(); i++ {
+		if s.Get(i) {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
 		}
 	}
+	return b.String()
+}
+
+// Clone returns a copy of the set.
+func (s *Set) Clone() *Set {
+	return s.cloneSize(s.length)
+}
+
+// Growable returns whether the set grows when bits beyond its end are set.
+func (s *Set) Growable() bool {
+	return s.growable
+}
+
+// Shrink truncates the set to n bits and releases the words that are no
+// longer needed.
+func (s *Set) Shrink(n int) error {
+	if n < 0 || n > s.length {
+		return errOutOfRange
+	}
+
+	s.resized()
+	data := make([]uint64, wordsNeeded(n))
+	copy(data, s.data)
+	s.data = data
+	s.length = n
+	s.clearPadding()
+
+	return nil
+}
+
+// Compact shrinks the set to the smallest size that holds all bits set to
+// one, releasing any trailing zero words.
+func (s *Set) Compact() {
+	last, _ := s.PrevSet(s.length - 1)
+	s.Shrink(last + 1)
+}
+
+// fit makes sure that the set holds at least n bits, growing it if needed.
+// It returns false if the set is too small and not growable, or if n is
+// negative because computing it overflowed.
+func (s *Set) fit(n int) bool {
+	if n < 0 {
+		return false
+	}
+	if n <= s.length {
+		return true
+	}
+	if !s.growable {
+		return false
+	}
+
+	s.resized()
+	words := wordsNeeded(n)
+	if words > cap(s.data) {
+		data := make([]uint64, words, max(words, 2*cap(s.data)))
+		copy(data, s.data)
+		s.data = data
+	} else {
+		old := len(s.data)
+		s.data = s.data[:words]
+		clear(s.data[old:])
+	}
+	s.length = n
 
 	return true
 }
 
-func (s *Set) String() string {
-	slice := make([]string, s.Size())
-	for _, b := range s.data {
-		for b > 0 {
-			if (b & 0x08) > 0 {
-				slice = append(slice, "1")
-			} else {
-				slice = append(slice, "0")
-			}
-			b <<= 1
-		}
-		slice = append(slice, "0")
+// modified is called before the words in [lo, hi) are changed.
+func (s *Set) modified(lo, hi int) {
+	s.index = nil
+	if s.cow != nil {
+		s.cow.touch(s.data, lo, hi)
 	}
-	return strings.Join(slice, "")
 }
 
-// New returns a new bitset with a given size.
-func New(n int) *Set {
-	size := n / 8
-	if r := n % 8; r > 0 {
-		size = size + 1
+// resized is called before the size of the set is changed. Snapshots keep
+// sharing the words of the set, since resizing either moves the set to new
+// words or only adds words beyond the ones shared.
+func (s *Set) resized() {
+	s.index = nil
+}
+
+// cloneSize returns a copy of the set resized to n bits.
+func (s *Set) cloneSize(n int) *Set {
+	c := &Set{
+		data:     make([]uint64, wordsNeeded(n)),
+		length:   n,
+		growable: s.growable,
+	}
+	copy(c.data, s.data)
+	c.clearPadding()
+	return c
+}
+
+// lastWordMask returns the mask of the bits in use in the last word.
+func (s *Set) lastWordMask() uint64 {
+	if r := uint(s.length & (wordSize - 1)); r > 0 {
+		return allOnes >> (wordSize - r)
+	}
+	return allOnes
+}
+
+// clearPadding clears the bits beyond the size of the set in the last word.
+func (s *Set) clearPadding() {
+	if len(s.data) > 0 {
+		s.data[len(s.data)-1] &= s.lastWordMask()
 	}
+}
 
+// New returns a new bitset with a given size.
+func New(n int) *Set {
 	return &Set{
-		data: make([]byte, size),
+		data:   make([]uint64, wordsNeeded(n)),
+		length: n,
 	}
 }
+
+// NewGrowable returns a new bitset with a given initial size that grows to
+// fit any bit that is set beyond its end.
+func NewGrowable(n int) *Set {
+	s := New(n)
+	s.growable = true
+	return s
+}
+
+// wordsNeeded returns the number of words needed to hold n bits.
+func wordsNeeded(n int) int {
+	return (n + wordSize - 1) >> log2Word
+}
@@ -1,6 +1,9 @@
 package bitset
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestSet(t *testing.T) {
 	s := New(1)
@@ -15,7 +18,7 @@ func TestSet(t *testing.T) {
 func TestSize(t *testing.T) {
 	s := New(3)
 
-	if s.Size() != 8 {
+	if s.Size() != 3 {
 		t.Fatal("invalid size")
 	}
 }
@@ -49,6 +52,17 @@ func TestAll(t *testing.T) {
 	}
 }
 
+func TestAllExactSize(t *testing.T) {
+	s := New(3)
+	for i := 0; i < s.Size(); i++ {
+		s.Set(i)
+	}
+
+	if !s.All() {
+		t.Fatal("s.All() should return", true)
+	}
+}
+
 func TestAny(t *testing.T) {
 	s1 := New(3)
 	s1.Set(2)
@@ -65,7 +79,7 @@ func TestAny(t *testing.T) {
 }
 
 func TestNone(t *testing.T) {
-	s1 := New(2)
+	s1 := New(3)
 	s1.Set(2)
 
 	if s1.None() {
@@ -84,8 +98,8 @@ func TestString(t *testing.T) {
 	s.Set(1)
 	s.Set(2)
 
-	if s.String() != "01100000" {
-		t.Fatalf("s.String() should return %s, was %s", "01100000", s.String())
+	if s.String() != "0110" {
+		t.Fatalf("s.String() should return %s, was %s", "0110", s.String())
 	}
 }
 
@@ -96,6 +110,18 @@ func TestIndexTooBig(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestIndexMaxInt(t *testing.T) {
+	for _, s := range []*Set{New(2), NewGrowable(2)} {
+		if err := s.Set(math.MaxInt); err != errOutOfRange {
+			t.Errorf("s.Set(math.MaxInt) = %v; want = %v", err, errOutOfRange)
+		}
+		if err := s.Flip(math.MaxInt); err != errOutOfRange {
+			t.Errorf("s.Flip(math.MaxInt) = %v; want = %v", err, errOutOfRange)
+		}
+	}
+}
+
 func TestIndexTooSmall(t *testing.T) {
 	s := New(2)
 
@@ -113,3 +139,224 @@ func BenchmarkInsert(b *testing.B) {
 		bs.Set(i)
 	}
 }
+
+func TestGetOutOfRange(t *testing.T) {
+	s := New(3)
+
+	if s.Get(3) || s.Get(-1) {
+		t.Fatal("s.Get() should return", false)
+	}
+}
+
+func TestGrowable(t *testing.T) {
+	s := NewGrowable(0)
+
+	if err := s.Set(100); err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 101 {
+		t.Fatalf("s.Size() = %d; want = %d", s.Size(), 101)
+	}
+	if !s.Get(100) {
+		t.Fatal("s.Get() should return", true)
+	}
+
+	if err := s.Clear(500); err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 101 {
+		t.Fatalf("s.Size() = %d; want = %d", s.Size(), 101)
+	}
+
+	if err := s.SetRange(90, 200); err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 200 || s.Count() != 110 {
+		t.Fatalf("s.Size() = %d, s.Count() = %d; want = %d, %d", s.Size(), s.Count(), 200, 110)
+	}
+}
+
+func TestShrink(t *testing.T) {
+	s := newSetWith(200, 1, 70, 150)
+
+	if err := s.Shrink(100); err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 100 || s.Count() != 2 {
+		t.Fatalf("s.Size() = %d, s.Count() = %d; want = %d, %d", s.Size(), s.Count(), 100, 2)
+	}
+	if err := s.Shrink(101); err != errOutOfRange {
+		t.Fatal("s.Shrink() should return", errOutOfRange)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	s := NewGrowable(0)
+	s.Set(1000)
+	s.Set(3)
+	s.Clear(1000)
+
+	s.Compact()
+
+	if s.Size() != 4 {
+		t.Fatalf("s.Size() = %d; want = %d", s.Size(), 4)
+	}
+	if len(s.data) != 1 {
+		t.Fatalf("len(s.data) = %d; want = %d", len(s.data), 1)
+	}
+}
+
+// byteSet is the previous byte-oriented layout, kept as a baseline for the
+// benchmarks below.
+type byteSet struct {
+	data []byte
+}
+
+func newByteSet(n int) *byteSet {
+	return &byteSet{data: make([]byte, (n+7)/8)}
+}
+
+func (s *byteSet) Set(i int) {
+	s.data[i/8] |= 1 << uint(i%8)
+}
+
+func (s *byteSet) Count() int {
+	var count int
+	for _, b := range s.data {
+		for b > 0 {
+			count = count + int(b&1)
+			b >>= 1
+		}
+	}
+	return count
+}
+
+func (s *byteSet) All() bool {
+	for _, b := range s.data {
+		if (b ^ 0xff) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *byteSet) Any() bool {
+	for _, b := range s.data {
+		if b > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+const benchSize = 10000000
+
+func newFullSet(n int) *Set {
+	s := New(n)
+	for i := 0; i < s.Size(); i++ {
+		s.Set(i)
+	}
+	return s
+}
+
+func newFullByteSet(n int) *byteSet {
+	s := newByteSet(n)
+	for i := 0; i < n; i++ {
+		s.Set(i)
+	}
+	return s
+}
+
+func BenchmarkCount(b *testing.B) {
+	s := newFullSet(benchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Count()
+	}
+}
+
+func BenchmarkCountBytes(b *testing.B) {
+	s := newFullByteSet(benchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Count()
+	}
+}
+
+func BenchmarkAll(b *testing.B) {
+	s := newFullSet(benchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.All()
+	}
+}
+
+func BenchmarkAllBytes(b *testing.B) {
+	s := newFullByteSet(benchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.All()
+	}
+}
+
+func BenchmarkAny(b *testing.B) {
+	s := New(benchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Any()
+	}
+}
+
+func BenchmarkAnyBytes(b *testing.B) {
+	s := newByteSet(benchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Any()
+	}
+}
+
+func TestClear(t *testing.T) {
+	s := New(8)
+	s.Set(3)
+
+	if err := s.Clear(3); err != nil {
+		t.Fatal(err)
+	}
+	if s.Get(3) {
+		t.Fatal("s.Get() should be", false)
+	}
+	if err := s.Clear(8); err != errOutOfRange {
+		t.Fatal("s.Clear() should return", errOutOfRange)
+	}
+}
+
+func TestFlip(t *testing.T) {
+	s := New(8)
+
+	s.Flip(5)
+	if !s.Get(5) {
+		t.Fatal("s.Get() should be", true)
+	}
+
+	s.Flip(5)
+	if s.Get(5) {
+		t.Fatal("s.Get() should be", false)
+	}
+	if err := s.Flip(-1); err != errOutOfRange {
+		t.Fatal("s.Flip() should return", errOutOfRange)
+	}
+}
+
+func TestSetTo(t *testing.T) {
+	s := New(8)
+
+	s.SetTo(2, true)
+	if !s.Get(2) {
+		t.Fatal("s.Get() should be", true)
+	}
+
+	s.SetTo(2, false)
+	if s.Get(2) {
+		t.Fatal("s.Get() should be", false)
+	}
+}
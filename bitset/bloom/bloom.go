@@ -0,0 +1,157 @@
+// Package bloom implements a Bloom filter on top of bitset.Set.
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/marcusolsson/exp/bitset"
+)
+
+var (
+	errIncompatible    = errors.New("incompatible filters")
+	errInvalidEncoding = errors.New("invalid encoding")
+)
+
+// Filter is a Bloom filter. Callers supply a single 64-bit hash for each
+// item, from which the filter derives the k bit positions using double
+// hashing.
+type Filter struct {
+	bits *bitset.Set
+	k    int
+}
+
+// New returns a filter sized to hold n items with a false positive rate of
+// at most p.
+func New(n int, p float64) *Filter {
+	if p <= 0 || p >= 1 {
+		panic("bloom: false positive rate must be in (0, 1)")
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	m := int(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+
+	return NewWithSize(m, k)
+}
+
+// NewWithSize returns a filter with m bits and k hash functions.
+func NewWithSize(m, k int) *Filter {
+	return &Filter{
+		bits: bitset.New(max(m, 1)),
+		k:    max(k, 1),
+	}
+}
+
+// Add adds an item with the given hash to the filter.
+func (f *Filter) Add(h uint64) {
+	for i := 0; i < f.k; i++ {
+		f.bits.Set(f.location(h, i))
+	}
+}
+
+// Test returns whether an item with the given hash may be in the filter. It
+// never returns false for an item that has been added.
+func (f *Filter) Test(h uint64) bool {
+	for i := 0; i < f.k; i++ {
+		if !f.bits.Get(f.location(h, i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// location returns the i-th bit position for a hash, using the lower and
+// upper halves of the hash as the two hash functions.
+func (f *Filter) location(h uint64, i int) int {
+	h1, h2 := h&math.MaxUint32, h>>32
+
+	// A 32-bit hash widened to 64 bits has no upper half, which would put
+	// all k positions on the same bit. Derive the second hash from the
+	// first one instead.
+	if h2 == 0 {
+		h2 = (h1*0x9e3779b97f4a7c15)>>32 | 1
+	}
+
+	return int((h1 + uint64(i)*h2) % uint64(f.bits.Size()))
+}
+
+// M returns the number of bits in the filter.
+func (f *Filter) M() int {
+	return f.bits.Size()
+}
+
+// K returns the number of hash functions used by the filter.
+func (f *Filter) K() int {
+	return f.k
+}
+
+// FillRatio returns the fraction of bits that are set.
+func (f *Filter) FillRatio() float64 {
+	return float64(f.bits.Count()) / float64(f.bits.Size())
+}
+
+// EstimatedCount returns an estimate of the number of items that have been
+// added to the filter.
+func (f *Filter) EstimatedCount() float64 {
+	m, k := float64(f.bits.Size()), float64(f.k)
+	return -m / k * math.Log(1-f.FillRatio())
+}
+
+// FalsePositiveRate returns the estimated false positive rate given the
+// current fill of the filter.
+func (f *Filter) FalsePositiveRate() float64 {
+	return math.Pow(f.FillRatio(), float64(f.k))
+}
+
+// Merge adds all items in other to f. Both filters need to have the same
+// size and number of hash functions.
+func (f *Filter) Merge(other *Filter) error {
+	if f.k != other.k || f.bits.Size() != other.bits.Size() {
+		return errIncompatible
+	}
+
+	f.bits.InPlaceUnion(other.bits)
+
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The number of hash
+// functions is encoded as a little endian uint64, followed by the binary
+// encoding of the underlying bitset.Set.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	b, err := f.bits.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(binary.LittleEndian.AppendUint64(nil, uint64(f.k)), b...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return errInvalidEncoding
+	}
+
+	k := binary.LittleEndian.Uint64(data)
+	if k < 1 || k > math.MaxInt32 {
+		return errInvalidEncoding
+	}
+
+	var bits bitset.Set
+	if err := bits.UnmarshalBinary(data[8:]); err != nil {
+		return err
+	}
+	if bits.Size() == 0 {
+		return errInvalidEncoding
+	}
+
+	f.bits = &bits
+	f.k = int(k)
+
+	return nil
+}
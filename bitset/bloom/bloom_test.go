@@ -0,0 +1,128 @@
+package bloom
+
+import (
+	"hash/fnv"
+	"strconv"
+	"testing"
+)
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func TestNew(t *testing.T) {
+	f := New(1000, 0.01)
+
+	// The optimal size for these parameters is 9586 bits and 7 hashes.
+	if f.M() != 9586 {
+		t.Errorf("f.M() = %d; want = %d", f.M(), 9586)
+	}
+	if f.K() != 7 {
+		t.Errorf("f.K() = %d; want = %d", f.K(), 7)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	const n = 1000
+	f := New(n, 0.01)
+
+	for i := 0; i < n; i++ {
+		f.Add(hash("item" + strconv.Itoa(i)))
+	}
+
+	for i := 0; i < n; i++ {
+		if !f.Test(hash("item" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for item%d", i)
+		}
+	}
+
+	var fp int
+	for i := 0; i < 10000; i++ {
+		if f.Test(hash("other" + strconv.Itoa(i))) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / 10000; rate > 0.03 {
+		t.Errorf("false positive rate = %f; want <= %f", rate, 0.03)
+	}
+
+	if est := f.EstimatedCount(); est < 900 || est > 1100 {
+		t.Errorf("f.EstimatedCount() = %f; want ~%d", est, n)
+	}
+	if rate := f.FalsePositiveRate(); rate > 0.02 {
+		t.Errorf("f.FalsePositiveRate() = %f; want ~%f", rate, 0.01)
+	}
+}
+
+func TestFilter32BitHash(t *testing.T) {
+	const n = 1000
+	f := New(n, 0.01)
+
+	hash32 := func(s string) uint64 {
+		h := fnv.New32a()
+		h.Write([]byte(s))
+		return uint64(h.Sum32())
+	}
+
+	// The k positions of an item must not collapse onto one bit.
+	f.Add(hash32("item"))
+	if got := f.bits.Count(); got != f.K() {
+		t.Errorf("f.bits.Count() = %d; want = %d", got, f.K())
+	}
+
+	for i := 0; i < n; i++ {
+		f.Add(hash32("item" + strconv.Itoa(i)))
+	}
+
+	var fp int
+	for i := 0; i < 10*n; i++ {
+		if f.Test(hash32("other" + strconv.Itoa(i))) {
+			fp++
+		}
+	}
+
+	if rate := float64(fp) / (10 * n); rate > 0.02 {
+		t.Errorf("false positive rate = %.4f; want <= %.4f", rate, 0.02)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := New(100, 0.01), New(100, 0.01)
+	a.Add(hash("a"))
+	b.Add(hash("b"))
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if !a.Test(hash("a")) || !a.Test(hash("b")) {
+		t.Error("merged filter is missing items")
+	}
+
+	if err := a.Merge(New(200, 0.01)); err != errIncompatible {
+		t.Errorf("a.Merge() = %v; want = %v", err, errIncompatible)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	f := New(100, 0.01)
+	f.Add(hash("a"))
+
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Filter
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.M() != f.M() || got.K() != f.K() || !got.Test(hash("a")) {
+		t.Error("decoded filter differs from the original")
+	}
+
+	if err := got.UnmarshalBinary(b[:4]); err != errInvalidEncoding {
+		t.Errorf("got.UnmarshalBinary() = %v; want = %v", err, errInvalidEncoding)
+	}
+}
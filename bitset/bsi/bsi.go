@@ -0,0 +1,155 @@
+// Package bsi implements a bit-sliced index over unsigned integer values.
+package bsi
+
+import (
+	"math/bits"
+
+	"github.com/marcusolsson/exp/bitset"
+)
+
+// Index stores a uint64 value for each row ID. It keeps one bitset.Set for
+// every bit position of the values, so comparisons against a constant are
+// answered with a few set operations per bit instead of one check per row.
+type Index struct {
+	exists *bitset.Set
+	planes []*bitset.Set
+}
+
+// New returns a new empty index.
+func New() *Index {
+	return &Index{
+		exists: bitset.NewGrowable(0),
+	}
+}
+
+// Set sets the value of a row.
+func (x *Index) Set(row int, v uint64) error {
+	if err := x.exists.Set(row); err != nil {
+		return err
+	}
+
+	for len(x.planes) < bits.Len64(v) {
+		x.planes = append(x.planes, bitset.NewGrowable(0))
+	}
+
+	for i, p := range x.planes {
+		p.SetTo(row, v&(1<<uint(i)) != 0)
+	}
+
+	return nil
+}
+
+// Get returns the value of a row. The second return value is false if the
+// row has no value.
+func (x *Index) Get(row int) (uint64, bool) {
+	if !x.exists.Get(row) {
+		return 0, false
+	}
+
+	var v uint64
+	for i, p := range x.planes {
+		if p.Get(row) {
+			v |= 1 << uint(i)
+		}
+	}
+	return v, true
+}
+
+// Clear removes the value of a row.
+func (x *Index) Clear(row int) error {
+	if err := x.exists.Clear(row); err != nil {
+		return err
+	}
+	for _, p := range x.planes {
+		p.Clear(row)
+	}
+	return nil
+}
+
+// Rows returns the set of rows that have a value.
+func (x *Index) Rows() *bitset.Set {
+	return x.fixed(x.exists)
+}
+
+// Equal returns the set of rows with a value equal to v.
+func (x *Index) Equal(v uint64) *bitset.Set {
+	_, eq, _ := x.compare(v)
+	return eq
+}
+
+// LessThan returns the set of rows with a value less than v.
+func (x *Index) LessThan(v uint64) *bitset.Set {
+	lt, _, _ := x.compare(v)
+	return lt
+}
+
+// GreaterThan returns the set of rows with a value greater than v.
+func (x *Index) GreaterThan(v uint64) *bitset.Set {
+	_, _, gt := x.compare(v)
+	return gt
+}
+
+// Range returns the set of rows with a value in [lo, hi].
+func (x *Index) Range(lo, hi uint64) *bitset.Set {
+	if lo > hi {
+		return bitset.New(x.exists.Size())
+	}
+
+	_, eqLo, gtLo := x.compare(lo)
+	ltHi, eqHi, _ := x.compare(hi)
+
+	gtLo.InPlaceUnion(eqLo)
+	ltHi.InPlaceUnion(eqHi)
+	gtLo.InPlaceIntersection(ltHi)
+
+	return gtLo
+}
+
+// Sum returns the sum of the values of the rows in filter, along with the
+// number of rows that were summed. A nil filter includes all rows. The sum
+// wraps around on overflow.
+func (x *Index) Sum(filter *bitset.Set) (uint64, int) {
+	rows := x.exists
+	if filter != nil {
+		rows = x.exists.Intersection(filter)
+	}
+
+	var sum uint64
+	for i, p := range x.planes {
+		sum += uint64(p.IntersectionCardinality(rows)) << uint(i)
+	}
+
+	return sum, rows.Count()
+}
+
+// compare returns the rows with values less than, equal to and greater
+// than v. The planes are walked from the most significant bit, narrowing
+// down the rows that are equal to v so far.
+func (x *Index) compare(v uint64) (lt, eq, gt *bitset.Set) {
+	n := x.exists.Size()
+	lt, eq, gt = bitset.New(n), x.fixed(x.exists), bitset.New(n)
+
+	if bits.Len64(v) > len(x.planes) {
+		return eq, bitset.New(n), gt
+	}
+
+	for i := len(x.planes) - 1; i >= 0; i-- {
+		p := x.planes[i]
+		if v&(1<<uint(i)) != 0 {
+			lt.InPlaceUnion(eq.Difference(p))
+			eq.InPlaceIntersection(p)
+		} else {
+			gt.InPlaceUnion(eq.Intersection(p))
+			eq.InPlaceDifference(p)
+		}
+	}
+
+	return lt, eq, gt
+}
+
+// fixed returns a copy of s with the size of the index that does not grow.
+func (x *Index) fixed(s *bitset.Set) *bitset.Set {
+	res := bitset.New(x.exists.Size())
+	res.InPlaceUnion(s)
+	return res
+}
@@ -0,0 +1,77 @@
+package bsi
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/marcusolsson/exp/bitset"
+)
+
+func TestSetGet(t *testing.T) {
+	x := New()
+	x.Set(3, 100)
+	x.Set(10, 0)
+	x.Set(3, 7)
+
+	if v, ok := x.Get(3); v != 7 || !ok {
+		t.Errorf("x.Get(3) = %d, %v; want = %d, %v", v, ok, 7, true)
+	}
+	if v, ok := x.Get(10); v != 0 || !ok {
+		t.Errorf("x.Get(10) = %d, %v; want = %d, %v", v, ok, 0, true)
+	}
+	if _, ok := x.Get(4); ok {
+		t.Errorf("x.Get(4) should not have a value")
+	}
+
+	x.Clear(3)
+	if _, ok := x.Get(3); ok {
+		t.Errorf("x.Get(3) should not have a value")
+	}
+	if err := x.Set(-1, 1); err == nil {
+		t.Error("x.Set(-1) should fail")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	x := New()
+	values := make(map[int]uint64)
+	for row := 0; row < 2000; row++ {
+		if rnd.Intn(5) == 0 {
+			continue
+		}
+		v := uint64(rnd.Intn(300))
+		x.Set(row, v)
+		values[row] = v
+	}
+
+	check := func(name string, got *bitset.Set, pred func(v uint64) bool) {
+		t.Helper()
+		for row := 0; row < 2000; row++ {
+			v, ok := values[row]
+			if want := ok && pred(v); got.Get(row) != want {
+				t.Fatalf("%s: row %d with value %d = %v; want = %v", name, row, v, got.Get(row), want)
+			}
+		}
+	}
+
+	for _, c := range []uint64{0, 1, 100, 255, 256, 299, 1000} {
+		check("Equal", x.Equal(c), func(v uint64) bool { return v == c })
+		check("LessThan", x.LessThan(c), func(v uint64) bool { return v < c })
+		check("GreaterThan", x.GreaterThan(c), func(v uint64) bool { return v > c })
+		check("Range", x.Range(c, c+50), func(v uint64) bool { return v >= c && v <= c+50 })
+	}
+
+	var sum uint64
+	filter := bitset.New(2000)
+	for row, v := range values {
+		if v > 100 {
+			sum += v
+			filter.Set(row)
+		}
+	}
+	if got, n := x.Sum(x.GreaterThan(100)); got != sum || n != filter.Count() {
+		t.Errorf("x.Sum() = %d, %d; want = %d, %d", got, n, sum, filter.Count())
+	}
+}
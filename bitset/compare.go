@@ -0,0 +1,90 @@
+package bitset
+
+// Equal returns whether s and other have the same size and bits.
+func (s *Set) Equal(other *Set) bool {
+	if s.length != other.length {
+		return false
+	}
+	for i, w := range s.data {
+		if w != other.data[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubsetOf returns whether all bits set in s are also set in other.
+func (s *Set) IsSubsetOf(other *Set) bool {
+	n := min(len(s.data), len(other.data))
+	for i := 0; i < n; i++ {
+		if s.data[i]&^other.data[i] != 0 {
+			return false
+		}
+	}
+	for _, w := range s.data[n:] {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSupersetOf returns whether all bits set in other are also set in s.
+func (s *Set) IsSupersetOf(other *Set) bool {
+	return other.IsSubsetOf(s)
+}
+
+// Intersects returns whether s and other have any bits set in common.
+func (s *Set) Intersects(other *Set) bool {
+	n := min(len(s.data), len(other.data))
+	for i := 0; i < n; i++ {
+		if s.data[i]&other.data[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// Hash returns a 64-bit FNV-1a hash of the size and bits of the set. Equal
+// sets have the same hash, and the hash is stable across processes, so it
+// can be used as a map key or persisted.
+func (s *Set) Hash() uint64 {
+	h := uint64(fnvOffset)
+	h = hashWord(h, uint64(s.length))
+	for _, w := range s.data {
+		h = hashWord(h, w)
+	}
+	return h
+}
+
+// hashWord adds the bytes of a word to h, least significant byte first.
+func hashWord(h, w uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= w & 0xff
+		h *= fnvPrime
+		w >>= 8
+	}
+	return h
+}
+
+// HammingDistance returns the number of bits that differ between s and
+// other.
+func (s *Set) HammingDistance(other *Set) int {
+	return s.SymmetricDifferenceCardinality(other)
+}
+
+// JaccardSimilarity returns the size of the intersection of s and other
+// divided by the size of their union. Two empty sets have a similarity
+// of 1.
+func (s *Set) JaccardSimilarity(other *Set) float64 {
+	union := s.UnionCardinality(other)
+	if union == 0 {
+		return 1
+	}
+	return float64(s.IntersectionCardinality(other)) / float64(union)
+}
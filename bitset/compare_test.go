@@ -0,0 +1,94 @@
+package bitset
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	a := newSetWith(100, 1, 70)
+	b := newSetWith(100, 1, 70)
+
+	if !a.Equal(b) {
+		t.Error("a.Equal(b) should return", true)
+	}
+	if a.Hash() != b.Hash() {
+		t.Error("equal sets should have equal hashes")
+	}
+
+	b.Set(2)
+	if a.Equal(b) {
+		t.Error("a.Equal(b) should return", false)
+	}
+
+	c := newSetWith(101, 1, 70)
+	if a.Equal(c) || a.Hash() == c.Hash() {
+		t.Error("sets of different size should not be equal")
+	}
+}
+
+func TestHashMapKey(t *testing.T) {
+	m := map[uint64]string{
+		newSetWith(8, 1).Hash(): "read",
+		newSetWith(8, 2).Hash(): "write",
+	}
+
+	if m[newSetWith(8, 2).Hash()] != "write" {
+		t.Error("unexpected map lookup")
+	}
+}
+
+func TestSubset(t *testing.T) {
+	a := newSetWith(64, 1, 5)
+	b := newSetWith(200, 1, 5, 150)
+
+	if !a.IsSubsetOf(b) || a.IsSupersetOf(b) {
+		t.Error("a should be a subset of b")
+	}
+	if b.IsSubsetOf(a) || !b.IsSupersetOf(a) {
+		t.Error("b should be a superset of a")
+	}
+	if !a.IsSubsetOf(a) {
+		t.Error("a set should be a subset of itself")
+	}
+}
+
+func TestIntersects(t *testing.T) {
+	a := newSetWith(100, 1, 80)
+
+	if !a.Intersects(newSetWith(200, 80, 150)) {
+		t.Error("a.Intersects() should return", true)
+	}
+	if a.Intersects(newSetWith(200, 2, 150)) {
+		t.Error("a.Intersects() should return", false)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	a := newSetWith(100, 1, 2, 3, 4)
+	b := newSetWith(100, 3, 4, 5, 6)
+
+	if d := a.HammingDistance(b); d != 4 {
+		t.Errorf("a.HammingDistance(b) = %d; want = %d", d, 4)
+	}
+	if j := a.JaccardSimilarity(b); j != 2.0/6.0 {
+		t.Errorf("a.JaccardSimilarity(b) = %f; want = %f", j, 2.0/6.0)
+	}
+	if j := New(10).JaccardSimilarity(New(10)); j != 1 {
+		t.Errorf("JaccardSimilarity() = %f; want = %f", j, 1.0)
+	}
+}
+
+func TestCompareDoesNotAllocate(t *testing.T) {
+	a := newSetWith(1000, 1, 500)
+	b := newSetWith(1000, 1, 999)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		a.Equal(b)
+		a.IsSubsetOf(b)
+		a.Intersects(b)
+		a.Hash()
+		a.HammingDistance(b)
+		a.JaccardSimilarity(b)
+	})
+	if allocs != 0 {
+		t.Errorf("allocs = %f; want = %d", allocs, 0)
+	}
+}
@@ -0,0 +1,212 @@
+package bitset
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+)
+
+// The binary encoding of a set consists of a 16 byte header followed by the
+// words of the set:
+//
+//	magic      [4]byte  "BSET"
+//	version    uint8    1
+//	byte order uint8    0 for little endian, 1 for big endian
+//	reserved   [2]byte  zero
+//	length     uint64   number of bits, in the given byte order
+//	words      []uint64 (length+63)/64 words, in the given byte order
+//
+// Sets are always encoded in little endian but both byte orders are accepted
+// when decoding.
+
+const (
+	headerSize    = 16
+	formatVersion = 1
+
+	littleEndian = 0
+	bigEndian    = 1
+)
+
+var magic = [4]byte{'B', 'S', 'E', 'T'}
+
+var (
+	errInvalidEncoding    = errors.New("invalid encoding")
+	errUnsupportedVersion = errors.New("unsupported version")
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s *Set) MarshalBinary() ([]byte, error) {
+	b := make([]byte, headerSize, headerSize+len(s.data)*8)
+	putHeader(b, s.length)
+	for _, w := range s.data {
+		b = binary.LittleEndian.AppendUint64(b, w)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (s *Set) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize {
+		return errInvalidEncoding
+	}
+
+	order, length, err := parseHeader(data[:headerSize])
+	if err != nil {
+		return err
+	}
+
+	data = data[headerSize:]
+	if len(data) != wordsNeeded(length)*8 {
+		return errInvalidEncoding
+	}
+
+	s.decodeWords(order, length, data)
+
+	return nil
+}
+
+// WriteTo implements io.WriterTo.
+func (s *Set) WriteTo(w io.Writer) (int64, error) {
+	b, err := s.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// ReadFrom implements io.ReaderFrom. It reads exactly one encoded set from r,
+// leaving any following data unread.
+func (s *Set) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil {
+		return int64(n), unexpectedEOF(err)
+	}
+
+	order, length, err := parseHeader(header)
+	if err != nil {
+		return int64(n), err
+	}
+
+	// Don't trust the length in the header with an allocation up front.
+	// The buffer grows with the data actually read instead.
+	var data bytes.Buffer
+	m, err := io.CopyN(&data, r, int64(wordsNeeded(length))*8)
+	if err != nil {
+		return int64(n) + m, unexpectedEOF(err)
+	}
+
+	s.decodeWords(order, length, data.Bytes())
+
+	return int64(n) + m, nil
+}
+
+// MarshalText implements encoding.TextMarshaler. The text form of a set is
+// the same as the one returned by String.
+func (s *Set) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Set) UnmarshalText(text []byte) error {
+	data := make([]uint64, wordsNeeded(len(text)))
+	for i, c := range text {
+		switch c {
+		case '0':
+		case '1':
+			data[i>>log2Word] |= 1 << uint(i&(wordSize-1))
+		default:
+			return errInvalidEncoding
+		}
+	}
+
+	s.resized()
+	s.data = data
+	s.length = len(text)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. A set is encoded as a string holding
+// the base64 encoding of its binary form.
+func (s *Set) MarshalJSON() ([]byte, error) {
+	b, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(b)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Set) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return err
+	}
+
+	return s.UnmarshalBinary(b)
+}
+
+func putHeader(b []byte, length int) {
+	copy(b, magic[:])
+	b[4] = formatVersion
+	b[5] = littleEndian
+	b[6], b[7] = 0, 0
+	binary.LittleEndian.PutUint64(b[8:], uint64(length))
+}
+
+func parseHeader(b []byte) (binary.ByteOrder, int, error) {
+	if [4]byte(b[:4]) != magic {
+		return nil, 0, errInvalidEncoding
+	}
+	if b[4] != formatVersion {
+		return nil, 0, errUnsupportedVersion
+	}
+
+	var order binary.ByteOrder
+	switch b[5] {
+	case littleEndian:
+		order = binary.LittleEndian
+	case bigEndian:
+		order = binary.BigEndian
+	default:
+		return nil, 0, errInvalidEncoding
+	}
+
+	length := order.Uint64(b[8:])
+	if length > math.MaxInt-wordSize {
+		return nil, 0, errInvalidEncoding
+	}
+
+	return order, int(length), nil
+}
+
+// decodeWords replaces the contents of the set with the encoded words in
+// data.
+func (s *Set) decodeWords(order binary.ByteOrder, length int, data []byte) {
+	s.resized()
+	s.data = make([]uint64, wordsNeeded(length))
+	for i := range s.data {
+		s.data[i] = order.Uint64(data[i*8:])
+	}
+	s.length = length
+	s.clearPadding()
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
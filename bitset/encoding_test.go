@@ -0,0 +1,157 @@
+package bitset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 63, 64, 65, 1000} {
+		s := New(n)
+		for i := 0; i < n; i += 3 {
+			s.Set(i)
+		}
+
+		b, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != headerSize+wordsNeeded(n)*8 {
+			t.Errorf("len(b) = %d; want = %d", len(b), headerSize+wordsNeeded(n)*8)
+		}
+
+		var got Set
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != s.String() {
+			t.Errorf("got = %s; want = %s", got.String(), s.String())
+		}
+	}
+}
+
+func TestUnmarshalBinaryBigEndian(t *testing.T) {
+	b := []byte{'B', 'S', 'E', 'T', formatVersion, bigEndian, 0, 0}
+	b = binary.BigEndian.AppendUint64(b, 70)
+	b = binary.BigEndian.AppendUint64(b, 1<<5)
+	b = binary.BigEndian.AppendUint64(b, 1<<1)
+
+	var s Set
+	if err := s.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := indices(&s), []int{5, 65}; !equalInts(got, want) {
+		t.Errorf("got = %v; want = %v", got, want)
+	}
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	valid, _ := newSetWith(70, 1).MarshalBinary()
+
+	for _, tt := range []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"short", valid[:8], errInvalidEncoding},
+		{"magic", append([]byte("XSET"), valid[4:]...), errInvalidEncoding},
+		{"version", append(append([]byte("BSET"), 2), valid[5:]...), errUnsupportedVersion},
+		{"truncated", valid[:len(valid)-1], errInvalidEncoding},
+	} {
+		var s Set
+		if err := s.UnmarshalBinary(tt.data); err != tt.want {
+			t.Errorf("%s: err = %v; want = %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	a := newSetWith(100, 1, 99)
+	b := newSetWith(10, 4)
+
+	var buf bytes.Buffer
+	if _, err := a.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotA, gotB Set
+	if _, err := gotA.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gotB.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if gotA.String() != a.String() || gotB.String() != b.String() {
+		t.Errorf("got = %s, %s; want = %s, %s", gotA.String(), gotB.String(), a.String(), b.String())
+	}
+}
+
+func TestReadFromHugeLength(t *testing.T) {
+	// A header that claims far more data than follows must fail without
+	// allocating for the claimed length.
+	header := make([]byte, headerSize)
+	putHeader(header, 0)
+	binary.LittleEndian.PutUint64(header[8:], 1<<62)
+
+	data := append(header, make([]byte, 8)...)
+
+	var s Set
+	if _, err := s.ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Error("ReadFrom: expected an error")
+	}
+	if err := s.UnmarshalBinary(data); err == nil {
+		t.Error("UnmarshalBinary: expected an error")
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	s := newSetWith(5, 1, 4)
+
+	text, err := s.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "01001" {
+		t.Errorf("text = %s; want = %s", text, "01001")
+	}
+
+	var got Set
+	if err := got.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if got.Size() != 5 || got.String() != s.String() {
+		t.Errorf("got = %s; want = %s", got.String(), s.String())
+	}
+
+	if err := got.UnmarshalText([]byte("012")); err != errInvalidEncoding {
+		t.Errorf("err = %v; want = %v", err, errInvalidEncoding)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	in := struct {
+		Flags *Set
+	}{
+		Flags: newSetWith(130, 0, 129),
+	}
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Flags *Set
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Flags.String() != in.Flags.String() {
+		t.Errorf("got = %s; want = %s", out.Flags.String(), in.Flags.String())
+	}
+}
@@ -0,0 +1,129 @@
+package bitset
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// Enum is the constraint for the constants stored in an EnumSet.
+type Enum interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// MaxEnum is the largest value an EnumSet holds. It keeps a stray large
+// constant, such as a bit flag like 1<<40, from growing the set to gigabytes.
+const MaxEnum = 1<<16 - 1
+
+// EnumSet is a set of integer constants, such as permission or capability
+// flags, stored in a growable Set. Values must be between 0 and MaxEnum. The
+// zero value is an empty set ready to use.
+type EnumSet[T Enum] struct {
+	set *Set
+}
+
+// NewEnumSet returns a new set holding the given values.
+func NewEnumSet[T Enum](values ...T) *EnumSet[T] {
+	s := &EnumSet[T]{}
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s
+}
+
+// Add adds a value to the set. Values below 0 or above MaxEnum are out of
+// range.
+func (s *EnumSet[T]) Add(v T) error {
+	i, ok := enumIndex(v)
+	if !ok {
+		return errOutOfRange
+	}
+	return s.bits().Set(i)
+}
+
+// Remove removes a value from the set.
+func (s *EnumSet[T]) Remove(v T) error {
+	i, ok := enumIndex(v)
+	if !ok {
+		return errOutOfRange
+	}
+	return s.bits().Clear(i)
+}
+
+// Has returns whether a value is in the set.
+func (s *EnumSet[T]) Has(v T) bool {
+	i, ok := enumIndex(v)
+	return ok && s.bits().Get(i)
+}
+
+// Len returns the number of values in the set.
+func (s *EnumSet[T]) Len() int {
+	return s.bits().Count()
+}
+
+// Values returns an iterator over the values in the set, in increasing
+// order.
+func (s *EnumSet[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := range s.bits().Indices() {
+			if !yield(T(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Union returns a new set with the values in either s or other.
+func (s *EnumSet[T]) Union(other *EnumSet[T]) *EnumSet[T] {
+	return &EnumSet[T]{set: s.bits().Union(other.bits())}
+}
+
+// Intersection returns a new set with the values in both s and other.
+func (s *EnumSet[T]) Intersection(other *EnumSet[T]) *EnumSet[T] {
+	return &EnumSet[T]{set: s.bits().Intersection(other.bits())}
+}
+
+// Difference returns a new set with the values in s but not in other.
+func (s *EnumSet[T]) Difference(other *EnumSet[T]) *EnumSet[T] {
+	return &EnumSet[T]{set: s.bits().Difference(other.bits())}
+}
+
+// SymmetricDifference returns a new set with the values in exactly one of s
+// and other.
+func (s *EnumSet[T]) SymmetricDifference(other *EnumSet[T]) *EnumSet[T] {
+	return &EnumSet[T]{set: s.bits().SymmetricDifference(other.bits())}
+}
+
+// String returns the values in the set, such as {Read Write}. Values are
+// formatted with their String method if they have one.
+func (s *EnumSet[T]) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for v := range s.Values() {
+		if b.Len() > 1 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprint(&b, v)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// bits returns the underlying set, creating it if needed.
+func (s *EnumSet[T]) bits() *Set {
+	if s.set == nil {
+		s.set = NewGrowable(0)
+	}
+	return s.set
+}
+
+// enumIndex returns the bit index of a value, or false if it is out of
+// range. The value is checked before converting it, since converting a large
+// unsigned value to int may make it negative.
+func enumIndex[T Enum](v T) (int, bool) {
+	if v < 0 || uint64(v) > MaxEnum {
+		return 0, false
+	}
+	return int(v), true
+}
@@ -0,0 +1,105 @@
+package bitset
+
+import "testing"
+
+type permission uint8
+
+const (
+	read permission = iota
+	write
+	execute
+)
+
+func (p permission) String() string {
+	switch p {
+	case read:
+		return "Read"
+	case write:
+		return "Write"
+	case execute:
+		return "Execute"
+	}
+	return "Unknown"
+}
+
+func TestEnumSet(t *testing.T) {
+	var s EnumSet[permission]
+
+	s.Add(read)
+	s.Add(execute)
+
+	if !s.Has(read) || s.Has(write) || !s.Has(execute) {
+		t.Fatal("unexpected values")
+	}
+	if s.Len() != 2 {
+		t.Fatalf("s.Len() = %d; want = %d", s.Len(), 2)
+	}
+	if got := s.String(); got != "{Read Execute}" {
+		t.Errorf("s.String() = %s; want = %s", got, "{Read Execute}")
+	}
+
+	s.Remove(read)
+	var got []permission
+	for p := range s.Values() {
+		got = append(got, p)
+	}
+	if len(got) != 1 || got[0] != execute {
+		t.Errorf("s.Values() = %v; want = %v", got, []permission{execute})
+	}
+}
+
+func TestEnumSetAlgebra(t *testing.T) {
+	a := NewEnumSet(read, write)
+	b := NewEnumSet(write, execute)
+
+	for _, tt := range []struct {
+		name string
+		got  *EnumSet[permission]
+		want string
+	}{
+		{"Union", a.Union(b), "{Read Write Execute}"},
+		{"Intersection", a.Intersection(b), "{Write}"},
+		{"Difference", a.Difference(b), "{Read}"},
+		{"SymmetricDifference", a.SymmetricDifference(b), "{Read Execute}"},
+	} {
+		if tt.got.String() != tt.want {
+			t.Errorf("%s = %s; want = %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestEnumSetWithoutStringer(t *testing.T) {
+	type level int
+
+	s := NewEnumSet[level](3, 1)
+	if got := s.String(); got != "{1 3}" {
+		t.Errorf("s.String() = %s; want = %s", got, "{1 3}")
+	}
+	if err := s.Add(-1); err != errOutOfRange {
+		t.Errorf("s.Add() = %v; want = %v", err, errOutOfRange)
+	}
+}
+
+func TestEnumSetOutOfRange(t *testing.T) {
+	var s EnumSet[uint64]
+
+	for _, v := range []uint64{MaxEnum + 1, 1 << 40, 1 << 63} {
+		if err := s.Add(v); err != errOutOfRange {
+			t.Errorf("s.Add(%d) = %v; want = %v", v, err, errOutOfRange)
+		}
+		if s.Has(v) {
+			t.Errorf("s.Has(%d) = true; want = false", v)
+		}
+	}
+	if err := s.Add(MaxEnum); err != nil {
+		t.Errorf("s.Add(%d) = %v; want = %v", MaxEnum, err, nil)
+	}
+
+	var signed EnumSet[int64]
+	if err := signed.Add(-1); err != errOutOfRange {
+		t.Errorf("signed.Add(-1) = %v; want = %v", err, errOutOfRange)
+	}
+	if err := signed.Remove(-1); err != errOutOfRange {
+		t.Errorf("signed.Remove(-1) = %v; want = %v", err, errOutOfRange)
+	}
+}
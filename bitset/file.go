@@ -0,0 +1,304 @@
+package bitset
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+	"math/bits"
+	"os"
+)
+
+var errReadOnly = errors.New("read-only set")
+
+// FileSet is a fixed size bitset stored outside of memory, using the same
+// binary encoding as Set. Sets created with Create or Open are backed by a
+// memory mapped file, while sets returned by NewReaderAt are read-only and
+// read words on demand.
+type FileSet struct {
+	// data holds the mapped file, including the header.
+	data []byte
+	file *os.File
+
+	r   io.ReaderAt
+	err error
+
+	order  binary.ByteOrder
+	length int
+}
+
+var _ Bitmap = (*FileSet)(nil)
+
+// Create creates a file holding a set of n zero bits and maps it into
+// memory. Any existing file is truncated.
+func Create(path string, n int) (*FileSet, error) {
+	if n < 0 {
+		return nil, errOutOfRange
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	putHeader(header, n)
+
+	if _, err := f.Write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Truncate(int64(headerSize + wordsNeeded(n)*8)); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return mapFile(f)
+}
+
+// Open maps an existing set file into memory for reading and writing.
+func Open(path string) (*FileSet, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapFile(f)
+}
+
+// NewReaderAt returns a read-only set that reads the encoded set from r.
+// Errors from r are reported by Err.
+func NewReaderAt(r io.ReaderAt) (*FileSet, error) {
+	header := make([]byte, headerSize)
+	if err := readAt(r, header, 0); err != nil {
+		return nil, err
+	}
+
+	order, length, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSet{r: r, order: order, length: length}, nil
+}
+
+func mapFile(f *os.File) (*FileSet, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		f.Close()
+		return nil, unexpectedEOF(err)
+	}
+
+	order, length, err := parseHeader(header)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	size := headerSize + wordsNeeded(length)*8
+	if fi.Size() != int64(size) {
+		f.Close()
+		return nil, errInvalidEncoding
+	}
+
+	data, err := mmap(f, size)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &FileSet{data: data, file: f, order: order, length: length}, nil
+}
+
+// Set sets a bit to 1.
+func (s *FileSet) Set(i int) error {
+	if s.data == nil {
+		return errReadOnly
+	}
+	if i < 0 || i >= s.length {
+		return errOutOfRange
+	}
+
+	w := i >> log2Word
+	s.putWord(w, s.word(w)|1<<uint(i&(wordSize-1)))
+
+	return nil
+}
+
+// Clear sets a bit to 0.
+func (s *FileSet) Clear(i int) error {
+	if s.data == nil {
+		return errReadOnly
+	}
+	if i < 0 || i >= s.length {
+		return errOutOfRange
+	}
+
+	w := i >> log2Word
+	s.putWord(w, s.word(w)&^(1<<uint(i&(wordSize-1))))
+
+	return nil
+}
+
+// Get returns whether a bit is set or not.
+func (s *FileSet) Get(i int) bool {
+	if i < 0 || i >= s.length {
+		return false
+	}
+	return s.word(i>>log2Word)&(1<<uint(i&(wordSize-1))) != 0
+}
+
+// Size returns the number of bits, both ones and zeroes.
+func (s *FileSet) Size() int {
+	return s.length
+}
+
+// Count returns the number of bits set to one.
+func (s *FileSet) Count() int {
+	var count int
+	for _, word := range s.words() {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// Any tests whether any bit is set.
+func (s *FileSet) Any() bool {
+	for _, word := range s.words() {
+		if word != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// None tests if no bits are set.
+func (s *FileSet) None() bool {
+	return !s.Any()
+}
+
+// Indices returns an iterator over the indices of the bits set to one, in
+// increasing order.
+func (s *FileSet) Indices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for w, word := range s.words() {
+			for word != 0 {
+				if !yield(w<<log2Word + bits.TrailingZeros64(word)) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+// Sync flushes changes to the underlying file.
+func (s *FileSet) Sync() error {
+	if s.data == nil {
+		return nil
+	}
+	if err := msync(s.file, s.data); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close flushes changes and releases the underlying file. The set must not
+// be used after it has been closed.
+func (s *FileSet) Close() error {
+	if s.data == nil {
+		return nil
+	}
+
+	err := s.Sync()
+	if uerr := munmap(s.data); err == nil {
+		err = uerr
+	}
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	s.data = nil
+
+	return err
+}
+
+// Err returns the first error encountered while reading from the
+// io.ReaderAt of a read-only set.
+func (s *FileSet) Err() error {
+	return s.err
+}
+
+// word returns the w-th word of the set.
+func (s *FileSet) word(w int) uint64 {
+	if s.data != nil {
+		return s.order.Uint64(s.data[headerSize+w*8:])
+	}
+
+	var b [8]byte
+	if err := readAt(s.r, b[:], int64(headerSize+w*8)); err != nil {
+		s.setErr(err)
+		return 0
+	}
+	return s.order.Uint64(b[:])
+}
+
+func (s *FileSet) putWord(w int, word uint64) {
+	s.order.PutUint64(s.data[headerSize+w*8:], word)
+}
+
+// words returns an iterator over the words of the set. Read-only sets are
+// read in larger chunks.
+func (s *FileSet) words() iter.Seq2[int, uint64] {
+	return func(yield func(int, uint64) bool) {
+		n := wordsNeeded(s.length)
+
+		if s.data != nil {
+			for w := 0; w < n; w++ {
+				if !yield(w, s.word(w)) {
+					return
+				}
+			}
+			return
+		}
+
+		buf := make([]byte, 8*1024)
+		for w := 0; w < n; {
+			chunk := buf[:min(len(buf), (n-w)*8)]
+			if err := readAt(s.r, chunk, int64(headerSize+w*8)); err != nil {
+				s.setErr(err)
+				return
+			}
+			for i := 0; i < len(chunk); i += 8 {
+				if !yield(w, s.order.Uint64(chunk[i:])) {
+					return
+				}
+				w++
+			}
+		}
+	}
+}
+
+// readAt fills p from r at off. Like io.ReaderAt allows, an error returned
+// together with a full p is ignored, such as io.EOF at the end of the input.
+func readAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return unexpectedEOF(err)
+}
+
+func (s *FileSet) setErr(err error) {
+	if s.err == nil {
+		s.err = unexpectedEOF(err)
+	}
+}
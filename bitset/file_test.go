@@ -0,0 +1,134 @@
+package bitset
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set")
+
+	s, err := Create(path, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, 64, 999} {
+		if err := s.Set(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Clear(64)
+	if err := s.Set(1000); err != errOutOfRange {
+		t.Errorf("s.Set() = %v; want = %v", err, errOutOfRange)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if s.Size() != 1000 || s.Count() != 2 || !s.Get(0) || !s.Get(999) || s.Get(64) {
+		t.Errorf("unexpected contents after reopening")
+	}
+
+	// The file uses the binary encoding of Set.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mem Set
+	if err := mem.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := indices(&mem), []int{0, 999}; !equalInts(got, want) {
+		t.Errorf("got = %v; want = %v", got, want)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	bad := filepath.Join(dir, "bad")
+	os.WriteFile(bad, []byte("not a bitset file"), 0644)
+	if _, err := Open(bad); err != errInvalidEncoding {
+		t.Errorf("Open() = %v; want = %v", err, errInvalidEncoding)
+	}
+
+	b, _ := New(1000).MarshalBinary()
+	truncated := filepath.Join(dir, "truncated")
+	os.WriteFile(truncated, b[:len(b)-8], 0644)
+	if _, err := Open(truncated); err != errInvalidEncoding {
+		t.Errorf("Open() = %v; want = %v", err, errInvalidEncoding)
+	}
+}
+
+func TestNewReaderAt(t *testing.T) {
+	src := New(100000)
+	for i := 0; i < src.Size(); i += 99 {
+		src.Set(i)
+	}
+	b, _ := src.MarshalBinary()
+
+	s, err := NewReaderAt(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Count() != src.Count() {
+		t.Errorf("s.Count() = %d; want = %d", s.Count(), src.Count())
+	}
+	if !s.Get(99) || s.Get(100) {
+		t.Error("unexpected contents")
+	}
+	if err := s.Set(1); err != errReadOnly {
+		t.Errorf("s.Set() = %v; want = %v", err, errReadOnly)
+	}
+	if s.Err() != nil {
+		t.Fatal(s.Err())
+	}
+
+	// Readers may return io.EOF along with the last bytes.
+	s, err = NewReaderAt(eofReaderAt{bytes.NewReader(b)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Count() != src.Count() || !s.Get(99) || s.Err() != nil {
+		t.Errorf("s.Count() = %d, s.Err() = %v; want = %d, %v", s.Count(), s.Err(), src.Count(), nil)
+	}
+
+	empty, _ := New(0).MarshalBinary()
+	if _, err := NewReaderAt(eofReaderAt{bytes.NewReader(empty)}); err != nil {
+		t.Errorf("NewReaderAt(empty) = %v; want = %v", err, nil)
+	}
+
+	// Reading past the end of a truncated source is reported by Err.
+	s, err = NewReaderAt(bytes.NewReader(b[:1000]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Count()
+	if s.Err() == nil {
+		t.Error("s.Err() should not be nil")
+	}
+}
+
+// eofReaderAt returns io.EOF together with the bytes that reach the end of
+// the input, as io.ReaderAt allows.
+type eofReaderAt struct {
+	r *bytes.Reader
+}
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.ReadAt(p, off)
+	if err == nil && off+int64(n) == r.r.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
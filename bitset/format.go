@@ -0,0 +1,157 @@
+package bitset
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var errInvalidSyntax = errors.New("invalid syntax")
+
+// maxParsedSize bounds the size of the sets returned by ParseRanges, so that
+// a typo in an index doesn't allocate gigabytes.
+const maxParsedSize = 1 << 32
+
+// Parse returns a set from a string of ones and zeroes, as returned by
+// String. The size of the set is the length of the string.
+func Parse(s string) (*Set, error) {
+	var res Set
+	if err := res.UnmarshalText([]byte(s)); err != nil {
+		return nil, errInvalidSyntax
+	}
+	return &res, nil
+}
+
+// ParseRanges returns a set from a comma separated list of indices and
+// inclusive ranges, such as "1-5,8,10-12". The size of the set is one more
+// than the largest index, which must be below 2^32.
+func ParseRanges(s string) (*Set, error) {
+	res := NewGrowable(0)
+
+	if strings.TrimSpace(s) == "" {
+		res.growable = false
+		return res, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+
+		start, err := parseIndex(lo)
+		if err != nil {
+			return nil, err
+		}
+
+		end := start
+		if isRange {
+			end, err = parseIndex(hi)
+			if err != nil {
+				return nil, err
+			}
+			if end < start {
+				return nil, errInvalidSyntax
+			}
+		}
+
+		if err := res.SetRange(start, end+1); err != nil {
+			return nil, err
+		}
+	}
+	res.growable = false
+
+	return res, nil
+}
+
+// parseIndex parses an index in a list of ranges.
+func parseIndex(s string) (int, error) {
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, errOutOfRange
+	}
+	if err != nil || i < 0 {
+		return 0, errInvalidSyntax
+	}
+	if uint64(i) >= maxParsedSize {
+		return 0, errOutOfRange
+	}
+	return i, nil
+}
+
+// Ranges returns the indices of the bits set to one as a comma separated
+// list of indices and inclusive ranges, in the format read by ParseRanges.
+func (s *Set) Ranges() string {
+	var b strings.Builder
+
+	for start, ok := s.NextSet(0); ok; start, ok = s.NextSet(start) {
+		end, ok := s.NextClear(start)
+		if !ok {
+			end = s.length
+		}
+
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(start))
+		if end-1 > start {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(end - 1))
+		}
+
+		start = end
+	}
+
+	return b.String()
+}
+
+// Format implements fmt.Formatter. The verbs are:
+//
+//	%b	the bits of the set, as returned by String
+//	%s	the same as %b
+//	%v	the indices of the bits set to one, such as {1 3 5}
+//	%x	the set as a hexadecimal number where bit i has the value 2^i
+//	%X	the same as %x, but with upper case letters
+//
+// The '#' flag adds a 0x prefix to %x and %X.
+func (s *Set) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'b', 's':
+		fmt.Fprint(f, s.String())
+	case 'v':
+		f.Write(s.appendIndices(nil))
+	case 'x', 'X':
+		digits := "0123456789abcdef"
+		if verb == 'X' {
+			digits = "0123456789ABCDEF"
+		}
+		if f.Flag('#') {
+			fmt.Fprint(f, "0x")
+		}
+		f.Write(s.appendHex(nil, digits))
+	default:
+		fmt.Fprintf(f, "%%!%c(*bitset.Set=%s)", verb, s.String())
+	}
+}
+
+func (s *Set) appendIndices(b []byte) []byte {
+	b = append(b, '{')
+	for i := range s.Indices() {
+		if len(b) > 1 {
+			b = append(b, ' ')
+		}
+		b = strconv.AppendInt(b, int64(i), 10)
+	}
+	return append(b, '}')
+}
+
+func (s *Set) appendHex(b []byte, digits string) []byte {
+	last, ok := s.PrevSet(s.length - 1)
+	if !ok {
+		return append(b, '0')
+	}
+
+	for n := last / 4; n >= 0; n-- {
+		nibble := s.data[n/16] >> (4 * uint(n%16)) & 0xf
+		b = append(b, digits[nibble])
+	}
+	return b
+}
@@ -0,0 +1,109 @@
+package bitset
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse("0110010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 7 {
+		t.Errorf("s.Size() = %d; want = %d", s.Size(), 7)
+	}
+	if got, want := indices(s), []int{1, 2, 5}; !equalInts(got, want) {
+		t.Errorf("got = %v; want = %v", got, want)
+	}
+
+	if _, err := Parse("01a"); err != errInvalidSyntax {
+		t.Errorf("Parse() = %v; want = %v", err, errInvalidSyntax)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	want := newSetWith(130, 0, 64, 129)
+
+	got, err := Parse(want.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("got = %s; want = %s", got, want)
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		size int
+		want []int
+	}{
+		{"", 0, nil},
+		{"3", 4, []int{3}},
+		{"1-5,8,10-12", 13, []int{1, 2, 3, 4, 5, 8, 10, 11, 12}},
+		{" 0 - 1 , 70 ", 71, []int{0, 1, 70}},
+	} {
+		s, err := ParseRanges(tt.in)
+		if err != nil {
+			t.Fatalf("ParseRanges(%q): %v", tt.in, err)
+		}
+		if s.Size() != tt.size {
+			t.Errorf("ParseRanges(%q).Size() = %d; want = %d", tt.in, s.Size(), tt.size)
+		}
+		if got := indices(s); !equalInts(got, tt.want) {
+			t.Errorf("ParseRanges(%q) = %v; want = %v", tt.in, got, tt.want)
+		}
+		if s.Growable() {
+			t.Errorf("ParseRanges(%q) should not be growable", tt.in)
+		}
+	}
+
+	for _, in := range []string{"a", "1-", "5-3", "-1", "1,,2"} {
+		if _, err := ParseRanges(in); err != errInvalidSyntax {
+			t.Errorf("ParseRanges(%q) = %v; want = %v", in, err, errInvalidSyntax)
+		}
+	}
+	for _, in := range []string{"9223372036854775807", "99999999999999999999", "0-99999999999", "4294967296"} {
+		if _, err := ParseRanges(in); err != errOutOfRange {
+			t.Errorf("ParseRanges(%q) = %v; want = %v", in, err, errOutOfRange)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	for _, in := range []string{"", "0", "1-5,8,10-12", "0-64,66-200"} {
+		s, err := ParseRanges(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Ranges(); got != in {
+			t.Errorf("Ranges() = %q; want = %q", got, in)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	s := newSetWith(70, 1, 3, 5, 68)
+
+	for _, tt := range []struct {
+		format string
+		want   string
+	}{
+		{"%v", "{1 3 5 68}"},
+		{"%b", s.String()},
+		{"%s", s.String()},
+		{"%x", "10000000000000002a"},
+		{"%#X", "0x10000000000000002A"},
+		{"%d", "%!d(*bitset.Set=" + s.String() + ")"},
+	} {
+		if got := fmt.Sprintf(tt.format, s); got != tt.want {
+			t.Errorf("Sprintf(%q) = %q; want = %q", tt.format, got, tt.want)
+		}
+	}
+
+	if got := fmt.Sprintf("%v %x", New(10), New(10)); got != "{} 0" {
+		t.Errorf("Sprintf() = %q; want = %q", got, "{} 0")
+	}
+}
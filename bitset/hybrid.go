@@ -0,0 +1,739 @@
+package bitset
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Hybrid is a fixed size bitset that stores its bits as a sorted array of
+// indices while few bits are set, and switches to a dense Set once the
+// array would use more memory than the dense words. It switches back to
+// the array once fewer than half as many bits are set.
+//
+// Hybrid has the same methods as Set. The ones that the array can't answer
+// efficiently work on the dense form, converting to it if needed.
+type Hybrid struct {
+	sparse []int
+
+	// dense is nil while the set is stored as an array.
+	dense *Set
+	count int
+
+	length int
+}
+
+var _ Bitmap = (*Hybrid)(nil)
+
+// NewHybrid returns a new hybrid bitset with a given size.
+func NewHybrid(n int) *Hybrid {
+	return &Hybrid{length: n}
+}
+
+// Set sets a bit to 1.
+func (h *Hybrid) Set(i int) error {
+	if i < 0 || i >= h.length {
+		return errOutOfRange
+	}
+
+	if h.dense != nil {
+		if !h.dense.Get(i) {
+			h.dense.Set(i)
+			h.count++
+		}
+		return nil
+	}
+
+	idx, ok := slices.BinarySearch(h.sparse, i)
+	if !ok {
+		h.sparse = slices.Insert(h.sparse, idx, i)
+		if len(h.sparse) > h.limit() {
+			h.toDense()
+		}
+	}
+
+	return nil
+}
+
+// Clear sets a bit to 0.
+func (h *Hybrid) Clear(i int) error {
+	if i < 0 || i >= h.length {
+		return errOutOfRange
+	}
+
+	if h.dense != nil {
+		if h.dense.Get(i) {
+			h.dense.Clear(i)
+			h.count--
+			if h.count < h.limit()/2 {
+				h.toSparse()
+			}
+		}
+		return nil
+	}
+
+	if idx, ok := slices.BinarySearch(h.sparse, i); ok {
+		h.sparse = slices.Delete(h.sparse, idx, idx+1)
+	}
+
+	return nil
+}
+
+// Flip toggles a bit.
+func (h *Hybrid) Flip(i int) error {
+	return h.SetTo(i, !h.Get(i))
+}
+
+// SetTo sets a bit to 1 if value is true, otherwise to 0.
+func (h *Hybrid) SetTo(i int, value bool) error {
+	if value {
+		return h.Set(i)
+	}
+	return h.Clear(i)
+}
+
+// Get returns whether a bit is set or not. Bits outside of the set are
+// reported as not set.
+func (h *Hybrid) Get(i int) bool {
+	if h.dense != nil {
+		return h.dense.Get(i)
+	}
+	_, ok := slices.BinarySearch(h.sparse, i)
+	return ok
+}
+
+// Size returns the number of bits, both ones and zeroes.
+func (h *Hybrid) Size() int {
+	return h.length
+}
+
+// Count returns the number of bits set to one.
+func (h *Hybrid) Count() int {
+	if h.dense != nil {
+		return h.count
+	}
+	return len(h.sparse)
+}
+
+// All tests whether all bits are set.
+func (h *Hybrid) All() bool {
+	return h.Count() == h.length
+}
+
+// Any tests whether any bit is set.
+func (h *Hybrid) Any() bool {
+	return h.Count() > 0
+}
+
+// None tests if no bits are set.
+func (h *Hybrid) None() bool {
+	return h.Count() == 0
+}
+
+// IsDense returns whether the set is currently stored as a dense Set.
+func (h *Hybrid) IsDense() bool {
+	return h.dense != nil
+}
+
+// String returns the bits of the set as a string of ones and zeroes, starting
+// with bit 0.
+func (h *Hybrid) String() string {
+	if h.dense != nil {
+		return h.dense.String()
+	}
+
+	b := []byte(strings.Repeat("0", h.length))
+	for _, i := range h.sparse {
+		b[i] = '1'
+	}
+	return string(b)
+}
+
+// Indices returns an iterator over the indices of the bits set to one, in
+// increasing order.
+func (h *Hybrid) Indices() iter.Seq[int] {
+	if h.dense != nil {
+		return h.dense.Indices()
+	}
+	return slices.Values(h.sparse)
+}
+
+// NextSet returns the index of the first bit set to one at or after i. The
+// second return value is false if there is no such bit.
+func (h *Hybrid) NextSet(i int) (int, bool) {
+	if h.dense != nil {
+		return h.dense.NextSet(i)
+	}
+
+	idx, _ := slices.BinarySearch(h.sparse, i)
+	if idx == len(h.sparse) {
+		return -1, false
+	}
+	return h.sparse[idx], true
+}
+
+// AppendTo appends the indices of the bits set to one to dst, in increasing
+// order, and returns the extended slice.
+func (h *Hybrid) AppendTo(dst []int) []int {
+	if h.dense != nil {
+		return h.dense.AppendTo(dst)
+	}
+	return append(dst, h.sparse...)
+}
+
+// Clone returns a copy of the set.
+func (h *Hybrid) Clone() *Hybrid {
+	res := &Hybrid{
+		sparse: slices.Clone(h.sparse),
+		count:  h.count,
+		length: h.length,
+	}
+	if h.dense != nil {
+		res.dense = h.dense.Clone()
+	}
+	return res
+}
+
+// Dense returns a copy of the set as a Set.
+func (h *Hybrid) Dense() *Set {
+	if h.dense != nil {
+		return h.dense.Clone()
+	}
+
+	res := New(h.length)
+	for _, i := range h.sparse {
+		res.data[i>>log2Word] |= 1 << uint(i&(wordSize-1))
+	}
+	return res
+}
+
+// Equal returns whether h and other have the same size and bits.
+func (h *Hybrid) Equal(other *Hybrid) bool {
+	if h.length != other.length || h.Count() != other.Count() {
+		return false
+	}
+	if h.dense == nil && other.dense == nil {
+		return slices.Equal(h.sparse, other.sparse)
+	}
+	return h.Dense().Equal(other.Dense())
+}
+
+// Union returns a new set with the bits set in either h or other. Like
+// Set.Union, the result has the size of the larger of the two.
+func (h *Hybrid) Union(other *Hybrid) *Hybrid {
+	return h.combine(other, opOr)
+}
+
+// Intersection returns a new set with the bits set in both h and other.
+func (h *Hybrid) Intersection(other *Hybrid) *Hybrid {
+	return h.combine(other, opAnd)
+}
+
+// Difference returns a new set with the bits set in h but not in other.
+func (h *Hybrid) Difference(other *Hybrid) *Hybrid {
+	return h.combine(other, opAndNot)
+}
+
+// SymmetricDifference returns a new set with the bits set in exactly one of
+// h and other.
+func (h *Hybrid) SymmetricDifference(other *Hybrid) *Hybrid {
+	return h.combine(other, opXor)
+}
+
+// InPlaceUnion sets the bits that are set in other. Like Set.InPlaceUnion,
+// the set keeps its size.
+func (h *Hybrid) InPlaceUnion(other *Hybrid) {
+	h.combineInPlace(other, opOr)
+}
+
+// InPlaceIntersection clears the bits that are not set in other.
+func (h *Hybrid) InPlaceIntersection(other *Hybrid) {
+	h.combineInPlace(other, opAnd)
+}
+
+// InPlaceDifference clears the bits that are set in other.
+func (h *Hybrid) InPlaceDifference(other *Hybrid) {
+	h.combineInPlace(other, opAndNot)
+}
+
+// InPlaceSymmetricDifference toggles the bits that are set in other.
+func (h *Hybrid) InPlaceSymmetricDifference(other *Hybrid) {
+	h.combineInPlace(other, opXor)
+}
+
+// UnionCardinality returns the number of bits set in either h or other.
+func (h *Hybrid) UnionCardinality(other *Hybrid) int {
+	return h.cardinality(other, opOr)
+}
+
+// IntersectionCardinality returns the number of bits set in both h and
+// other.
+func (h *Hybrid) IntersectionCardinality(other *Hybrid) int {
+	return h.cardinality(other, opAnd)
+}
+
+// DifferenceCardinality returns the number of bits set in h but not in
+// other.
+func (h *Hybrid) DifferenceCardinality(other *Hybrid) int {
+	return h.cardinality(other, opAndNot)
+}
+
+// SymmetricDifferenceCardinality returns the number of bits set in exactly
+// one of h and other.
+func (h *Hybrid) SymmetricDifferenceCardinality(other *Hybrid) int {
+	return h.cardinality(other, opXor)
+}
+
+// IsSubsetOf returns whether every bit set in h is also set in other.
+func (h *Hybrid) IsSubsetOf(other *Hybrid) bool {
+	return h.cardinality(other, opAndNot) == 0
+}
+
+// IsSupersetOf returns whether every bit set in other is also set in h.
+func (h *Hybrid) IsSupersetOf(other *Hybrid) bool {
+	return other.IsSubsetOf(h)
+}
+
+// Intersects returns whether h and other have any bit set in common.
+func (h *Hybrid) Intersects(other *Hybrid) bool {
+	return h.cardinality(other, opAnd) > 0
+}
+
+// Hash returns a hash of the size and bits of the set, equal to the hash of
+// the same bits in a Set.
+func (h *Hybrid) Hash() uint64 {
+	return h.view().Hash()
+}
+
+// HammingDistance returns the number of bits that differ between h and
+// other.
+func (h *Hybrid) HammingDistance(other *Hybrid) int {
+	return h.cardinality(other, opXor)
+}
+
+// JaccardSimilarity returns the size of the intersection of h and other
+// divided by the size of their union. Two empty sets have a similarity
+// of 1.
+func (h *Hybrid) JaccardSimilarity(other *Hybrid) float64 {
+	union := h.cardinality(other, opOr)
+	if union == 0 {
+		return 1
+	}
+	return float64(h.cardinality(other, opAnd)) / float64(union)
+}
+
+// NextClear returns the index of the first bit set to zero at or after i.
+// The second return value is false if there is no such bit.
+func (h *Hybrid) NextClear(i int) (int, bool) {
+	if h.dense != nil {
+		return h.dense.NextClear(i)
+	}
+
+	i = max(i, 0)
+	idx, _ := slices.BinarySearch(h.sparse, i)
+	for idx < len(h.sparse) && h.sparse[idx] == i {
+		idx++
+		i++
+	}
+	if i >= h.length {
+		return -1, false
+	}
+	return i, true
+}
+
+// PrevSet returns the index of the last bit set to one at or before i. The
+// second return value is false if there is no such bit.
+func (h *Hybrid) PrevSet(i int) (int, bool) {
+	if h.dense != nil {
+		return h.dense.PrevSet(i)
+	}
+
+	idx, ok := slices.BinarySearch(h.sparse, min(i, h.length-1))
+	if ok {
+		return h.sparse[idx], true
+	}
+	if idx == 0 {
+		return -1, false
+	}
+	return h.sparse[idx-1], true
+}
+
+// SetRange sets all bits in [start, end) to 1.
+func (h *Hybrid) SetRange(start, end int) error {
+	return h.updateRange(start, end, opOr, (*Set).SetRange)
+}
+
+// ClearRange sets all bits in [start, end) to 0.
+func (h *Hybrid) ClearRange(start, end int) error {
+	if err := h.checkRange(start, end); err != nil {
+		return err
+	}
+
+	if h.dense != nil {
+		h.update(func(s *Set) { s.ClearRange(start, end) })
+		return nil
+	}
+
+	lo, _ := slices.BinarySearch(h.sparse, start)
+	hi, _ := slices.BinarySearch(h.sparse, end)
+	h.sparse = slices.Delete(h.sparse, lo, hi)
+
+	return nil
+}
+
+// FlipRange toggles all bits in [start, end).
+func (h *Hybrid) FlipRange(start, end int) error {
+	return h.updateRange(start, end, opXor, (*Set).FlipRange)
+}
+
+// CountRange returns the number of bits set to one in [start, end).
+func (h *Hybrid) CountRange(start, end int) (int, error) {
+	if h.dense != nil {
+		return h.dense.CountRange(start, end)
+	}
+	if err := h.checkRange(start, end); err != nil {
+		return 0, err
+	}
+
+	lo, _ := slices.BinarySearch(h.sparse, start)
+	hi, _ := slices.BinarySearch(h.sparse, end)
+
+	return hi - lo, nil
+}
+
+// ShiftLeft moves every bit n positions towards higher indices, as
+// Set.ShiftLeft does.
+func (h *Hybrid) ShiftLeft(n int) {
+	h.update(func(s *Set) { s.ShiftLeft(n) })
+}
+
+// ShiftRight moves every bit n positions towards lower indices, as
+// Set.ShiftRight does.
+func (h *Hybrid) ShiftRight(n int) {
+	h.update(func(s *Set) { s.ShiftRight(n) })
+}
+
+// RotateLeft moves every bit n positions towards higher indices, wrapping
+// around to the start of the set.
+func (h *Hybrid) RotateLeft(n int) {
+	h.update(func(s *Set) { s.RotateLeft(n) })
+}
+
+// RotateRight moves every bit n positions towards lower indices, wrapping
+// around to the end of the set.
+func (h *Hybrid) RotateRight(n int) {
+	h.update(func(s *Set) { s.RotateRight(n) })
+}
+
+// BuildIndex builds a rank and select index over the dense form of the
+// set. The array needs no index.
+func (h *Hybrid) BuildIndex() {
+	if h.dense != nil {
+		h.dense.BuildIndex()
+	}
+}
+
+// Rank returns the number of bits set to one before i.
+func (h *Hybrid) Rank(i int) int {
+	if h.dense != nil {
+		return h.dense.Rank(i)
+	}
+	idx, _ := slices.BinarySearch(h.sparse, i)
+	return idx
+}
+
+// Select returns the index of the k-th bit set to one, counting from zero.
+// The second return value is false if fewer than k+1 bits are set.
+func (h *Hybrid) Select(k int) (int, bool) {
+	if h.dense != nil {
+		return h.dense.Select(k)
+	}
+	if k < 0 || k >= len(h.sparse) {
+		return -1, false
+	}
+	return h.sparse[k], true
+}
+
+// Growable returns false, since a hybrid set has a fixed size.
+func (h *Hybrid) Growable() bool {
+	return false
+}
+
+// Shrink truncates the set to n bits.
+func (h *Hybrid) Shrink(n int) error {
+	if n < 0 || n > h.length {
+		return errOutOfRange
+	}
+
+	if h.dense == nil {
+		idx, _ := slices.BinarySearch(h.sparse, n)
+		h.sparse = slices.Clip(h.sparse[:idx])
+		h.length = n
+		return nil
+	}
+
+	h.dense.Shrink(n)
+	h.length = n
+	h.update(func(*Set) {})
+
+	return nil
+}
+
+// Compact shrinks the set to the smallest size that holds all bits set to
+// one.
+func (h *Hybrid) Compact() {
+	last, _ := h.PrevSet(h.length - 1)
+	h.Shrink(last + 1)
+}
+
+// Snapshot returns an immutable view of the set as it is now. Unlike
+// Set.Snapshot, it copies the set.
+func (h *Hybrid) Snapshot() *Snapshot {
+	return h.Dense().Snapshot()
+}
+
+// Ranges returns the indices of the bits set to one as a comma separated
+// list of indices and inclusive ranges, as Set.Ranges does.
+func (h *Hybrid) Ranges() string {
+	return h.view().Ranges()
+}
+
+// Format implements fmt.Formatter with the same verbs as Set.
+func (h *Hybrid) Format(f fmt.State, verb rune) {
+	h.view().Format(f, verb)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, using the same
+// encoding as Set.
+func (h *Hybrid) MarshalBinary() ([]byte, error) {
+	return h.view().MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (h *Hybrid) UnmarshalBinary(data []byte) error {
+	var s Set
+	if err := s.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	h.load(&s)
+	return nil
+}
+
+// WriteTo implements io.WriterTo.
+func (h *Hybrid) WriteTo(w io.Writer) (int64, error) {
+	return h.view().WriteTo(w)
+}
+
+// ReadFrom implements io.ReaderFrom. It reads exactly one encoded set from
+// r.
+func (h *Hybrid) ReadFrom(r io.Reader) (int64, error) {
+	var s Set
+	n, err := s.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	h.load(&s)
+	return n, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (h *Hybrid) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *Hybrid) UnmarshalText(text []byte) error {
+	var s Set
+	if err := s.UnmarshalText(text); err != nil {
+		return err
+	}
+	h.load(&s)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, using the same encoding as Set.
+func (h *Hybrid) MarshalJSON() ([]byte, error) {
+	return h.view().MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (h *Hybrid) UnmarshalJSON(data []byte) error {
+	var s Set
+	if err := s.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	h.load(&s)
+	return nil
+}
+
+// view returns the set as a Set, without copying it if it is dense. The
+// result must not be modified.
+func (h *Hybrid) view() *Set {
+	if h.dense != nil {
+		return h.dense
+	}
+	return h.Dense()
+}
+
+// update applies f to the dense form of the set, and switches back to the
+// array if few enough bits remain set.
+func (h *Hybrid) update(f func(s *Set)) {
+	if h.dense == nil {
+		h.dense = h.Dense()
+		h.sparse = nil
+	}
+
+	f(h.dense)
+
+	h.count = h.dense.Count()
+	if h.count < h.limit()/2 {
+		h.toSparse()
+	}
+}
+
+// updateRange sets or toggles the bits in [start, end), in the array if
+// the result still fits in it, and otherwise with f on the dense form.
+func (h *Hybrid) updateRange(start, end int, op setOp, f func(s *Set, start, end int) error) error {
+	if err := h.checkRange(start, end); err != nil || start == end {
+		return err
+	}
+
+	if h.dense == nil && len(h.sparse)+end-start <= h.limit() {
+		r := make([]int, end-start)
+		for i := range r {
+			r[i] = start + i
+		}
+		h.sparse = mergeSorted(h.sparse, r, op)
+		return nil
+	}
+
+	h.update(func(s *Set) { f(s, start, end) })
+
+	return nil
+}
+
+func (h *Hybrid) checkRange(start, end int) error {
+	if start < 0 || end > h.length || start > end {
+		return errOutOfRange
+	}
+	return nil
+}
+
+// load replaces the contents of the set with s, which it takes over.
+func (h *Hybrid) load(s *Set) {
+	s.growable = false
+	h.length = s.length
+	h.dense = s
+	h.sparse = nil
+	h.update(func(*Set) {})
+}
+
+// cardinality returns the number of bits in the result of op. It only
+// allocates if one of the sets is sparse and the other is dense.
+func (h *Hybrid) cardinality(other *Hybrid, op setOp) int {
+	if h.dense == nil && other.dense == nil {
+		a, b := len(h.sparse), len(other.sparse)
+		both := intersectionCount(h.sparse, other.sparse)
+
+		switch op {
+		case opOr:
+			return a + b - both
+		case opAnd:
+			return both
+		case opAndNot:
+			return a - both
+		default:
+			return a + b - 2*both
+		}
+	}
+
+	a, b := h.view(), other.view()
+	switch op {
+	case opOr:
+		return a.UnionCardinality(b)
+	case opAnd:
+		return a.IntersectionCardinality(b)
+	case opAndNot:
+		return a.DifferenceCardinality(b)
+	default:
+		return a.SymmetricDifferenceCardinality(b)
+	}
+}
+
+// combineInPlace replaces the set with the result of op, keeping its size.
+func (h *Hybrid) combineInPlace(other *Hybrid, op setOp) {
+	res := h.combine(other, op)
+	if res.length > h.length {
+		res.Shrink(h.length)
+	}
+	*h = *res
+}
+
+func (h *Hybrid) combine(other *Hybrid, op setOp) *Hybrid {
+	res := &Hybrid{length: max(h.length, other.length)}
+
+	if h.dense == nil && other.dense == nil {
+		res.sparse = mergeSorted(h.sparse, other.sparse, op)
+		if len(res.sparse) > res.limit() {
+			res.toDense()
+		}
+		return res
+	}
+
+	a, b := h.view(), other.view()
+	switch op {
+	case opOr:
+		res.dense = a.Union(b)
+	case opAnd:
+		res.dense = a.Intersection(b)
+	case opAndNot:
+		res.dense = a.Difference(b)
+	default:
+		res.dense = a.SymmetricDifference(b)
+	}
+	res.count = res.dense.Count()
+	if res.count < res.limit()/2 {
+		res.toSparse()
+	}
+
+	return res
+}
+
+// limit returns the number of indices at which the array uses more memory
+// than the dense words.
+func (h *Hybrid) limit() int {
+	return max(wordsNeeded(h.length), 1)
+}
+
+func (h *Hybrid) toDense() {
+	h.dense = h.Dense()
+	h.count = len(h.sparse)
+	h.sparse = nil
+}
+
+func (h *Hybrid) toSparse() {
+	h.sparse = h.dense.AppendTo(nil)
+	h.dense = nil
+	h.count = 0
+}
+
+// intersectionCount returns the number of values in both of the sorted
+// slices a and b.
+func intersectionCount(a, b []int) int {
+	var n, i, j int
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			n++
+			i++
+			j++
+		}
+	}
+	return n
+}
@@ -0,0 +1,321 @@
+package bitset
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestHybrid(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	const n = 4096
+	h := NewHybrid(n)
+	s := New(n)
+
+	check := func() {
+		t.Helper()
+		if h.Count() != s.Count() {
+			t.Fatalf("h.Count() = %d; want = %d", h.Count(), s.Count())
+		}
+		if !h.Dense().Equal(s) {
+			t.Fatal("hybrid set differs from the dense set")
+		}
+		if got, want := slices.Collect(h.Indices()), s.AppendTo(nil); !slices.Equal(got, want) {
+			t.Fatal("unexpected indices")
+		}
+	}
+
+	// Fill until the set becomes dense.
+	for !h.IsDense() {
+		i := rnd.Intn(n)
+		h.Set(i)
+		s.Set(i)
+	}
+	if h.Count() != n/wordSize+1 {
+		t.Errorf("switched to dense at %d bits; want = %d", h.Count(), n/wordSize+1)
+	}
+	check()
+
+	for i := 0; i < 2*n; i++ {
+		j := rnd.Intn(n)
+		h.Flip(j)
+		s.Flip(j)
+	}
+	check()
+
+	// Empty the set until it becomes sparse again.
+	for _, i := range s.AppendTo(nil) {
+		h.Clear(i)
+		s.Clear(i)
+		if !h.IsDense() {
+			break
+		}
+	}
+	if h.Count() != n/wordSize/2-1 {
+		t.Errorf("switched to sparse at %d bits; want = %d", h.Count(), n/wordSize/2-1)
+	}
+	check()
+}
+
+func TestHybridQueries(t *testing.T) {
+	h := NewHybrid(10)
+	h.Set(3)
+	h.Set(7)
+
+	if h.String() != "0001000100" {
+		t.Errorf("h.String() = %s; want = %s", h.String(), "0001000100")
+	}
+	if i, ok := h.NextSet(4); i != 7 || !ok {
+		t.Errorf("h.NextSet(4) = %d, %v; want = %d, %v", i, ok, 7, true)
+	}
+	if _, ok := h.NextSet(8); ok {
+		t.Error("h.NextSet(8) should fail")
+	}
+	if h.All() || !h.Any() || h.None() {
+		t.Error("unexpected predicates")
+	}
+	if err := h.Set(10); err != errOutOfRange {
+		t.Errorf("h.Set() = %v; want = %v", err, errOutOfRange)
+	}
+}
+
+func TestHybridAlgebra(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+
+	random := func(n, k int) *Hybrid {
+		h := NewHybrid(n)
+		for i := 0; i < k; i++ {
+			h.Set(rnd.Intn(n))
+		}
+		return h
+	}
+
+	for _, tt := range []struct {
+		a, b *Hybrid
+	}{
+		{random(10000, 20), random(12000, 30)},
+		{random(10000, 20), random(10000, 5000)},
+		{random(10000, 5000), random(8000, 6000)},
+	} {
+		da, db := tt.a.Dense(), tt.b.Dense()
+
+		if !tt.a.Union(tt.b).Dense().Equal(da.Union(db)) {
+			t.Error("Union differs from the dense set")
+		}
+		if !tt.a.Intersection(tt.b).Dense().Equal(da.Intersection(db)) {
+			t.Error("Intersection differs from the dense set")
+		}
+		if !tt.a.Difference(tt.b).Dense().Equal(da.Difference(db)) {
+			t.Error("Difference differs from the dense set")
+		}
+		if !tt.a.SymmetricDifference(tt.b).Dense().Equal(da.SymmetricDifference(db)) {
+			t.Error("SymmetricDifference differs from the dense set")
+		}
+		if !tt.a.Equal(tt.a.Clone()) || tt.a.Equal(tt.b) {
+			t.Error("unexpected Equal")
+		}
+	}
+}
+
+func TestHybridMatchesSet(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+
+	// Ranges of up to 8 bits keep the set sparse, while ranges of up to
+	// 512 bits keep switching it between the two forms.
+	for _, width := range []int{8, 512} {
+		const n = 2048
+		h := NewHybrid(n)
+		s := New(n)
+
+		for i := 0; i < 500; i++ {
+			start := rnd.Intn(n)
+			end := min(start+rnd.Intn(width), n)
+
+			switch rnd.Intn(6) {
+			case 0, 1:
+				h.SetRange(start, end)
+				s.SetRange(start, end)
+			case 2:
+				h.ClearRange(start, end)
+				s.ClearRange(start, end)
+			case 3:
+				h.FlipRange(start, end)
+				s.FlipRange(start, end)
+			case 4:
+				h.ShiftLeft(end - start)
+				s.ShiftLeft(end - start)
+			case 5:
+				h.RotateRight(end - start)
+				s.RotateRight(end - start)
+			}
+
+			if !h.Dense().Equal(s) || h.Count() != s.Count() {
+				t.Fatalf("width %d: hybrid set differs from the dense set after %d operations", width, i)
+			}
+
+			j := rnd.Intn(n)
+			if got, want := h.Rank(j), s.Rank(j); got != want {
+				t.Fatalf("h.Rank(%d) = %d; want = %d", j, got, want)
+			}
+			if got, ok := h.NextClear(j); !equalIndex(got, ok)(s.NextClear(j)) {
+				t.Fatalf("h.NextClear(%d) = %d, %v", j, got, ok)
+			}
+			if got, ok := h.PrevSet(j); !equalIndex(got, ok)(s.PrevSet(j)) {
+				t.Fatalf("h.PrevSet(%d) = %d, %v", j, got, ok)
+			}
+			if got, ok := h.Select(j % (s.Count() + 1)); !equalIndex(got, ok)(s.Select(j % (s.Count() + 1))) {
+				t.Fatalf("h.Select() = %d, %v", got, ok)
+			}
+			got, _ := h.CountRange(start, end)
+			want, _ := s.CountRange(start, end)
+			if got != want {
+				t.Fatalf("h.CountRange(%d, %d) = %d; want = %d", start, end, got, want)
+			}
+		}
+
+		if h.Hash() != s.Hash() || h.Ranges() != s.Ranges() {
+			t.Errorf("width %d: unexpected Hash or Ranges", width)
+		}
+
+		b, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dec Hybrid
+		if err := dec.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if !dec.Equal(h) || dec.Growable() {
+			t.Errorf("width %d: decoded set differs from the original", width)
+		}
+
+		last, _ := s.PrevSet(n - 1)
+		h.Compact()
+		if h.Size() != last+1 {
+			t.Errorf("h.Size() = %d; want = %d", h.Size(), last+1)
+		}
+	}
+
+	if err := NewHybrid(10).SetRange(5, 11); err != errOutOfRange {
+		t.Errorf("h.SetRange() = %v; want = %v", err, errOutOfRange)
+	}
+}
+
+// equalIndex returns a function that reports whether an index and flag
+// match the ones returned by the Set method it is called with.
+func equalIndex(i int, ok bool) func(int, bool) bool {
+	return func(j int, want bool) bool {
+		return ok == want && (!ok || i == j)
+	}
+}
+
+func TestHybridInPlace(t *testing.T) {
+	rnd := rand.New(rand.NewSource(4))
+
+	random := func(n, k int) *Hybrid {
+		h := NewHybrid(n)
+		for i := 0; i < k; i++ {
+			h.Set(rnd.Intn(n))
+		}
+		return h
+	}
+
+	for _, tt := range []struct {
+		a, b *Hybrid
+	}{
+		{random(10000, 20), random(12000, 30)},
+		{random(10000, 20), random(10000, 5000)},
+		{random(10000, 5000), random(8000, 6000)},
+	} {
+		da, db := tt.a.Dense(), tt.b.Dense()
+
+		if got, want := tt.a.UnionCardinality(tt.b), da.UnionCardinality(db); got != want {
+			t.Errorf("UnionCardinality() = %d; want = %d", got, want)
+		}
+		if got, want := tt.a.IntersectionCardinality(tt.b), da.IntersectionCardinality(db); got != want {
+			t.Errorf("IntersectionCardinality() = %d; want = %d", got, want)
+		}
+		if got, want := tt.a.DifferenceCardinality(tt.b), da.DifferenceCardinality(db); got != want {
+			t.Errorf("DifferenceCardinality() = %d; want = %d", got, want)
+		}
+		if got, want := tt.a.HammingDistance(tt.b), da.HammingDistance(db); got != want {
+			t.Errorf("HammingDistance() = %d; want = %d", got, want)
+		}
+		if got, want := tt.a.JaccardSimilarity(tt.b), da.JaccardSimilarity(db); got != want {
+			t.Errorf("JaccardSimilarity() = %f; want = %f", got, want)
+		}
+		if tt.a.Intersects(tt.b) != da.Intersects(db) || tt.a.IsSubsetOf(tt.b) != da.IsSubsetOf(db) {
+			t.Error("unexpected Intersects or IsSubsetOf")
+		}
+		if !tt.a.IsSubsetOf(tt.a.Union(tt.b)) || !tt.a.Union(tt.b).IsSupersetOf(tt.b) {
+			t.Error("a set is not a subset of its union")
+		}
+
+		for _, op := range []struct {
+			name   string
+			hybrid func(h, other *Hybrid)
+			dense  func(s, other *Set)
+		}{
+			{"InPlaceUnion", (*Hybrid).InPlaceUnion, (*Set).InPlaceUnion},
+			{"InPlaceIntersection", (*Hybrid).InPlaceIntersection, (*Set).InPlaceIntersection},
+			{"InPlaceDifference", (*Hybrid).InPlaceDifference, (*Set).InPlaceDifference},
+			{"InPlaceSymmetricDifference", (*Hybrid).InPlaceSymmetricDifference, (*Set).InPlaceSymmetricDifference},
+		} {
+			h, s := tt.a.Clone(), da.Clone()
+			op.hybrid(h, tt.b)
+			op.dense(s, db)
+			if !h.Dense().Equal(s) || h.Size() != tt.a.Size() {
+				t.Errorf("%s differs from the dense set", op.name)
+			}
+		}
+	}
+}
+
+func TestHybridAllocs(t *testing.T) {
+	a, b := NewHybrid(100000), NewHybrid(100000)
+	for i := 0; i < 100; i++ {
+		a.Set(i * 7)
+		b.Set(i * 11)
+	}
+
+	if n := testing.AllocsPerRun(10, func() { a.SymmetricDifferenceCardinality(b) }); n != 0 {
+		t.Errorf("sparse cardinality allocs = %v; want = %v", n, 0)
+	}
+
+	a.SetRange(0, a.Size())
+	b.SetRange(0, b.Size())
+
+	// Combining two dense sets only allocates the result.
+	if n := testing.AllocsPerRun(10, func() { a.Union(b) }); n > 3 {
+		t.Errorf("dense union allocs = %v; want <= %v", n, 3)
+	}
+}
+
+func benchmarkFill(b *testing.B, newSet func() Bitmap, step int) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := newSet()
+		for j := 0; j < benchSize; j += step {
+			s.Set(j)
+		}
+		for range s.Indices() {
+		}
+	}
+}
+
+func BenchmarkHybridSparse(b *testing.B) {
+	benchmarkFill(b, func() Bitmap { return NewHybrid(benchSize) }, 100000)
+}
+
+func BenchmarkSetSparse(b *testing.B) {
+	benchmarkFill(b, func() Bitmap { return New(benchSize) }, 100000)
+}
+
+func BenchmarkHybridDense(b *testing.B) {
+	benchmarkFill(b, func() Bitmap { return NewHybrid(benchSize) }, 2)
+}
+
+func BenchmarkSetDense(b *testing.B) {
+	benchmarkFill(b, func() Bitmap { return New(benchSize) }, 2)
+}
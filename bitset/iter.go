@@ -0,0 +1,116 @@
+package bitset
+
+import (
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+// NextSet returns the index of the first bit set to one at or after i. The
+// second return value is false if there is no such bit.
+func (s *Set) NextSet(i int) (int, bool) {
+	if i < 0 {
+		i = 0
+	}
+	if i >= s.Size() {
+		return -1, false
+	}
+
+	w := i >> log2Word
+	word := s.data[w] >> uint(i&(wordSize-1))
+	if word != 0 {
+		return i + bits.TrailingZeros64(word), true
+	}
+
+	for w++; w < len(s.data); w++ {
+		if s.data[w] != 0 {
+			return w<<log2Word + bits.TrailingZeros64(s.data[w]), true
+		}
+	}
+
+	return -1, false
+}
+
+// NextClear returns the index of the first bit set to zero at or after i.
+// The second return value is false if there is no such bit.
+func (s *Set) NextClear(i int) (int, bool) {
+	if i < 0 {
+		i = 0
+	}
+	if i >= s.Size() {
+		return -1, false
+	}
+
+	w := i >> log2Word
+	word := ^s.data[w] >> uint(i&(wordSize-1))
+	if word != 0 {
+		if j := i + bits.TrailingZeros64(word); j < s.Size() {
+			return j, true
+		}
+		return -1, false
+	}
+
+	for w++; w < len(s.data); w++ {
+		if s.data[w] != allOnes {
+			if j := w<<log2Word + bits.TrailingZeros64(^s.data[w]); j < s.Size() {
+				return j, true
+			}
+			return -1, false
+		}
+	}
+
+	return -1, false
+}
+
+// PrevSet returns the index of the last bit set to one at or before i. The
+// second return value is false if there is no such bit.
+func (s *Set) PrevSet(i int) (int, bool) {
+	if i >= s.Size() {
+		i = s.Size() - 1
+	}
+	if i < 0 {
+		return -1, false
+	}
+
+	w := i >> log2Word
+	word := s.data[w] << uint(wordSize-1-i&(wordSize-1))
+	if word != 0 {
+		return i - bits.LeadingZeros64(word), true
+	}
+
+	for w--; w >= 0; w-- {
+		if s.data[w] != 0 {
+			return w<<log2Word + wordSize - 1 - bits.LeadingZeros64(s.data[w]), true
+		}
+	}
+
+	return -1, false
+}
+
+// Indices returns an iterator over the indices of the bits set to one, in
+// increasing order. Words without any bits set are skipped.
+func (s *Set) Indices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for w, word := range s.data {
+			for word != 0 {
+				if !yield(w<<log2Word + bits.TrailingZeros64(word)) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+// AppendTo appends the indices of the bits set to one to dst, in increasing
+// order, and returns the extended slice.
+func (s *Set) AppendTo(dst []int) []int {
+	dst = slices.Grow(dst, s.Count())
+	for w, word := range s.data {
+		for word != 0 {
+			dst = append(dst, w<<log2Word+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	return dst
+}
@@ -0,0 +1,116 @@
+package bitset
+
+import "testing"
+
+func TestNextSet(t *testing.T) {
+	s := newSetWith(256, 3, 64, 200)
+
+	for _, tt := range []struct {
+		in   int
+		want int
+		ok   bool
+	}{
+		{-5, 3, true},
+		{0, 3, true},
+		{3, 3, true},
+		{4, 64, true},
+		{65, 200, true},
+		{201, -1, false},
+		{256, -1, false},
+	} {
+		got, ok := s.NextSet(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NextSet(%d) = %d, %v; want = %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNextClear(t *testing.T) {
+	s := New(136)
+	s.SetRange(0, 130)
+
+	if got, ok := s.NextClear(0); got != 130 || !ok {
+		t.Errorf("NextClear(0) = %d, %v; want = %d, %v", got, ok, 130, true)
+	}
+
+	s.SetRange(130, 136)
+	if got, ok := s.NextClear(0); ok {
+		t.Errorf("NextClear(0) = %d, %v; want = %d, %v", got, ok, -1, false)
+	}
+}
+
+func TestPrevSet(t *testing.T) {
+	s := newSetWith(256, 3, 64, 200)
+
+	for _, tt := range []struct {
+		in   int
+		want int
+		ok   bool
+	}{
+		{1000, 200, true},
+		{200, 200, true},
+		{199, 64, true},
+		{63, 3, true},
+		{2, -1, false},
+	} {
+		got, ok := s.PrevSet(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("PrevSet(%d) = %d, %v; want = %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIndices(t *testing.T) {
+	want := []int{0, 63, 64, 127, 500}
+	s := newSetWith(512, want...)
+
+	var got []int
+	for i := range s.Indices() {
+		got = append(got, i)
+	}
+	if !equalInts(got, want) {
+		t.Errorf("got = %v; want = %v", got, want)
+	}
+
+	for i := range s.Indices() {
+		if i > 63 {
+			t.Fatal("iteration did not stop")
+		}
+		if i == 63 {
+			break
+		}
+	}
+}
+
+func TestAppendTo(t *testing.T) {
+	s := newSetWith(512, 1, 2, 300)
+
+	got := s.AppendTo([]int{-1})
+	if want := []int{-1, 1, 2, 300}; !equalInts(got, want) {
+		t.Errorf("got = %v; want = %v", got, want)
+	}
+}
+
+func BenchmarkIndicesSparse(b *testing.B) {
+	s := New(benchSize)
+	for i := 0; i < s.Size(); i += 10000 {
+		s.Set(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for range s.Indices() {
+		}
+	}
+}
+
+func BenchmarkAppendTo(b *testing.B) {
+	s := New(benchSize)
+	for i := 0; i < s.Size(); i += 3 {
+		s.Set(i)
+	}
+	buf := make([]int, 0, s.Count())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = s.AppendTo(buf[:0])
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || freebsd)
+
+package bitset
+
+import "os"
+
+// On platforms without mmap support the file is read into memory and
+// written back on Sync.
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func munmap(b []byte) error {
+	return nil
+}
+
+func msync(f *os.File, b []byte) error {
+	_, err := f.WriteAt(b, 0)
+	return err
+}
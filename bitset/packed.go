@@ -0,0 +1,129 @@
+package bitset
+
+import "iter"
+
+// PackedArray is a fixed length array of unsigned integers that are width
+// bits wide, packed back to back in the words of a Set. Values may straddle
+// word boundaries.
+type PackedArray struct {
+	bits  *Set
+	width int
+	n     int
+	mask  uint64
+}
+
+// NewPackedArray returns a new array of n zero values, each width bits
+// wide. The width must be between 1 and 64.
+func NewPackedArray(n, width int) *PackedArray {
+	if width < 1 || width > wordSize {
+		panic("bitset: width must be between 1 and 64")
+	}
+
+	return &PackedArray{
+		bits:  New(n * width),
+		width: width,
+		n:     n,
+		mask:  allOnes >> uint(wordSize-width),
+	}
+}
+
+// Len returns the number of values in the array.
+func (a *PackedArray) Len() int {
+	return a.n
+}
+
+// Width returns the number of bits in each value.
+func (a *PackedArray) Width() int {
+	return a.width
+}
+
+// Get returns the i-th value. Values outside of the array are reported as
+// zero.
+func (a *PackedArray) Get(i int) uint64 {
+	if i < 0 || i >= a.n {
+		return 0
+	}
+
+	pos := i * a.width
+	w, offset := pos>>log2Word, uint(pos&(wordSize-1))
+
+	v := a.bits.data[w] >> offset
+	if int(offset)+a.width > wordSize {
+		v |= a.bits.data[w+1] << (wordSize - offset)
+	}
+	return v & a.mask
+}
+
+// Set sets the i-th value. It returns errOutOfRange if i is outside of the
+// array or if v does not fit in the width of the array.
+func (a *PackedArray) Set(i int, v uint64) error {
+	if i < 0 || i >= a.n || v&^a.mask != 0 {
+		return errOutOfRange
+	}
+
+	pos := i * a.width
+	w, offset := pos>>log2Word, uint(pos&(wordSize-1))
+
+	if int(offset)+a.width > wordSize {
+		a.bits.modified(w, w+2)
+		a.bits.data[w] = a.bits.data[w]&^(a.mask<<offset) | v<<offset
+		a.bits.data[w+1] = a.bits.data[w+1]&^(a.mask>>(wordSize-offset)) | v>>(wordSize-offset)
+		return nil
+	}
+
+	a.bits.modified(w, w+1)
+	a.bits.data[w] = a.bits.data[w]&^(a.mask<<offset) | v<<offset
+
+	return nil
+}
+
+// Fill sets all values to v. The bit pattern of the filled array repeats
+// every width words, so only the first period is written value by value and
+// the rest is copied a word at a time.
+func (a *PackedArray) Fill(v uint64) error {
+	if v&^a.mask != 0 {
+		return errOutOfRange
+	}
+
+	data := a.bits.data
+	a.bits.modified(0, len(data))
+
+	period := a.width / gcd(a.width, wordSize)
+	values := min(a.n, period*wordSize/a.width)
+	clear(data[:min(period, len(data))])
+	for i := 0; i < values; i++ {
+		a.Set(i, v)
+	}
+
+	for w := period; w < len(data); w += period {
+		copy(data[w:], data[:period])
+	}
+	a.bits.clearPadding()
+
+	return nil
+}
+
+// All returns an iterator over the index and value of each element of the
+// array.
+func (a *PackedArray) All() iter.Seq2[int, uint64] {
+	return func(yield func(int, uint64) bool) {
+		for i := 0; i < a.n; i++ {
+			if !yield(i, a.Get(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Bits returns the Set holding the bits of the array. Value i occupies bits
+// [i*width, (i+1)*width) with the least significant bit first.
+func (a *PackedArray) Bits() *Set {
+	return a.bits
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,78 @@
+package bitset
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+func TestPackedArray(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, width := range []int{1, 2, 3, 5, 7, 13, 32, 63, 64} {
+		a := NewPackedArray(500, width)
+		want := make([]uint64, a.Len())
+		for i := range want {
+			want[i] = rnd.Uint64() & (allOnes >> uint(wordSize-width))
+			if err := a.Set(i, want[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for i, v := range a.All() {
+			if v != want[i] {
+				t.Fatalf("width %d: a.Get(%d) = %d; want = %d", width, i, v, want[i])
+			}
+		}
+	}
+}
+
+func TestPackedArrayOutOfRange(t *testing.T) {
+	a := NewPackedArray(10, 3)
+
+	if err := a.Set(10, 1); err != errOutOfRange {
+		t.Errorf("a.Set() = %v; want = %v", err, errOutOfRange)
+	}
+	if err := a.Set(0, 8); err != errOutOfRange {
+		t.Errorf("a.Set() = %v; want = %v", err, errOutOfRange)
+	}
+	if err := a.Fill(8); err != errOutOfRange {
+		t.Errorf("a.Fill() = %v; want = %v", err, errOutOfRange)
+	}
+	if a.Get(-1) != 0 || a.Get(10) != 0 {
+		t.Error("a.Get() should return 0 outside of the array")
+	}
+}
+
+func TestPackedArrayFill(t *testing.T) {
+	for _, width := range []int{1, 3, 5, 8, 24, 64} {
+		for _, n := range []int{0, 1, 10, 1000} {
+			a := NewPackedArray(n, width)
+			a.Set(0, 1)
+
+			v := uint64(5) & (allOnes >> uint(wordSize-width))
+			if err := a.Fill(v); err != nil {
+				t.Fatal(err)
+			}
+			for i, got := range a.All() {
+				if got != v {
+					t.Fatalf("width %d, n %d: a.Get(%d) = %d; want = %d", width, n, i, got, v)
+				}
+			}
+			if a.Bits().Size() != n*width {
+				t.Fatalf("a.Bits().Size() = %d; want = %d", a.Bits().Size(), n*width)
+			}
+			if want := n * bits.OnesCount64(v); a.Bits().Count() != want {
+				t.Fatalf("a.Bits().Count() = %d; want = %d", a.Bits().Count(), want)
+			}
+		}
+	}
+}
+
+func BenchmarkPackedArrayFill(b *testing.B) {
+	a := NewPackedArray(benchSize/5, 5)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.Fill(uint64(i & 31))
+	}
+}
@@ -0,0 +1,127 @@
+package bitset
+
+import "math/bits"
+
+// The range operations below work on the half-open range [start, end) and
+// return errOutOfRange unless 0 <= start <= end <= Size(). Growable sets are
+// extended by SetRange and FlipRange, while ClearRange and CountRange ignore
+// the part of the range beyond their end.
+
+// SetRange sets all bits in [start, end) to 1.
+func (s *Set) SetRange(start, end int) error {
+	if start >= 0 && start <= end {
+		s.fit(end)
+	}
+	if err := s.checkRange(start, end); err != nil || start == end {
+		return err
+	}
+
+	first, last, firstMask, lastMask := rangeWords(start, end)
+	s.modified(first, last+1)
+	if first == last {
+		s.data[first] |= firstMask & lastMask
+		return nil
+	}
+
+	s.data[first] |= firstMask
+	for w := first + 1; w < last; w++ {
+		s.data[w] = allOnes
+	}
+	s.data[last] |= lastMask
+
+	return nil
+}
+
+// ClearRange sets all bits in [start, end) to 0.
+func (s *Set) ClearRange(start, end int) error {
+	start, end = s.clampRange(start, end)
+	if err := s.checkRange(start, end); err != nil || start == end {
+		return err
+	}
+
+	first, last, firstMask, lastMask := rangeWords(start, end)
+	s.modified(first, last+1)
+	if first == last {
+		s.data[first] &^= firstMask & lastMask
+		return nil
+	}
+
+	s.data[first] &^= firstMask
+	clear(s.data[first+1 : last])
+	s.data[last] &^= lastMask
+
+	return nil
+}
+
+// FlipRange toggles all bits in [start, end).
+func (s *Set) FlipRange(start, end int) error {
+	if start >= 0 && start <= end {
+		s.fit(end)
+	}
+	if err := s.checkRange(start, end); err != nil || start == end {
+		return err
+	}
+
+	first, last, firstMask, lastMask := rangeWords(start, end)
+	s.modified(first, last+1)
+	if first == last {
+		s.data[first] ^= firstMask & lastMask
+		return nil
+	}
+
+	s.data[first] ^= firstMask
+	for w := first + 1; w < last; w++ {
+		s.data[w] = ^s.data[w]
+	}
+	s.data[last] ^= lastMask
+
+	return nil
+}
+
+// CountRange returns the number of bits set to one in [start, end).
+func (s *Set) CountRange(start, end int) (int, error) {
+	start, end = s.clampRange(start, end)
+	if err := s.checkRange(start, end); err != nil || start == end {
+		return 0, err
+	}
+
+	first, last, firstMask, lastMask := rangeWords(start, end)
+	if first == last {
+		return bits.OnesCount64(s.data[first] & firstMask & lastMask), nil
+	}
+
+	count := bits.OnesCount64(s.data[first] & firstMask)
+	for _, w := range s.data[first+1 : last] {
+		count += bits.OnesCount64(w)
+	}
+	count += bits.OnesCount64(s.data[last] & lastMask)
+
+	return count, nil
+}
+
+func (s *Set) checkRange(start, end int) error {
+	if start < 0 || end > s.Size() || start > end {
+		return errOutOfRange
+	}
+	return nil
+}
+
+// clampRange limits a valid range to the end of a growable set.
+func (s *Set) clampRange(start, end int) (int, int) {
+	if s.growable && start >= 0 && start <= end && end > s.length {
+		end = s.length
+		start = min(start, end)
+	}
+	return start, end
+}
+
+// rangeWords returns the first and last word of the non-empty range
+// [start, end) along with the masks of the bits in the range for each of
+// them.
+func rangeWords(start, end int) (first, last int, firstMask, lastMask uint64) {
+	first = start >> log2Word
+	last = (end - 1) >> log2Word
+	firstMask = allOnes << uint(start&(wordSize-1))
+	lastMask = allOnes >> uint(wordSize-1-(end-1)&(wordSize-1))
+	return first, last, firstMask, lastMask
+}
@@ -0,0 +1,92 @@
+package bitset
+
+import "testing"
+
+func TestSetRange(t *testing.T) {
+	for _, tt := range []struct {
+		start, end int
+	}{
+		{0, 0},
+		{3, 9},
+		{0, 64},
+		{60, 70},
+		{5, 200},
+		{64, 256},
+	} {
+		s := New(256)
+		if err := s.SetRange(tt.start, tt.end); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < s.Size(); i++ {
+			want := i >= tt.start && i < tt.end
+			if s.Get(i) != want {
+				t.Fatalf("SetRange(%d, %d): s.Get(%d) = %v; want = %v", tt.start, tt.end, i, s.Get(i), want)
+			}
+		}
+		if n, _ := s.CountRange(0, s.Size()); n != tt.end-tt.start {
+			t.Errorf("CountRange() = %d; want = %d", n, tt.end-tt.start)
+		}
+	}
+}
+
+func TestClearRange(t *testing.T) {
+	s := New(256)
+	s.SetRange(0, 256)
+
+	if err := s.ClearRange(10, 140); err != nil {
+		t.Fatal(err)
+	}
+	if s.Count() != 256-130 {
+		t.Errorf("s.Count() = %d; want = %d", s.Count(), 256-130)
+	}
+	if !s.Get(9) || s.Get(10) || s.Get(139) || !s.Get(140) {
+		t.Error("unexpected bits at the range boundaries")
+	}
+}
+
+func TestFlipRange(t *testing.T) {
+	s := New(128)
+	s.Set(1)
+	s.Set(70)
+
+	if err := s.FlipRange(0, 100); err != nil {
+		t.Fatal(err)
+	}
+	if s.Count() != 98 {
+		t.Errorf("s.Count() = %d; want = %d", s.Count(), 98)
+	}
+	if s.Get(1) || s.Get(70) || !s.Get(0) || !s.Get(99) || s.Get(100) {
+		t.Error("unexpected bits after FlipRange")
+	}
+}
+
+func TestCountRange(t *testing.T) {
+	s := newSetWith(256, 0, 63, 64, 65, 130, 255)
+
+	n, err := s.CountRange(63, 131)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("CountRange() = %d; want = %d", n, 4)
+	}
+}
+
+func TestRangeOutOfRange(t *testing.T) {
+	s := New(16)
+
+	for _, tt := range []struct {
+		start, end int
+	}{
+		{-1, 4},
+		{0, 17},
+		{5, 4},
+	} {
+		if err := s.SetRange(tt.start, tt.end); err != errOutOfRange {
+			t.Errorf("SetRange(%d, %d) = %v; want = %v", tt.start, tt.end, err, errOutOfRange)
+		}
+		if _, err := s.CountRange(tt.start, tt.end); err != errOutOfRange {
+			t.Errorf("CountRange(%d, %d) = %v; want = %v", tt.start, tt.end, err, errOutOfRange)
+		}
+	}
+}
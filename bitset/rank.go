@@ -0,0 +1,124 @@
+package bitset
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// rankIndex is a rank9 style index. For every block of eight words it holds
+// the number of bits set before the block, and the number of bits set
+// before each of the words 1-7 relative to the block, packed as 9-bit
+// counts.
+type rankIndex struct {
+	counts   []int
+	relative []uint64
+}
+
+const blockWords = 8
+
+// BuildIndex builds an index over the set that answers Rank in constant and
+// Select in logarithmic time. The index is dropped whenever the set is
+// modified and needs to be built again.
+func (s *Set) BuildIndex() {
+	blocks := (len(s.data) + blockWords - 1) / blockWords
+
+	idx := &rankIndex{
+		counts:   make([]int, blocks+1),
+		relative: make([]uint64, blocks),
+	}
+
+	var total int
+	for b := 0; b < blocks; b++ {
+		idx.counts[b] = total
+
+		var rel int
+		for j := 0; j < blockWords && b*blockWords+j < len(s.data); j++ {
+			if j > 0 {
+				idx.relative[b] |= uint64(rel) << (9 * uint(j-1))
+			}
+			rel += bits.OnesCount64(s.data[b*blockWords+j])
+		}
+		total += rel
+	}
+	idx.counts[blocks] = total
+
+	s.index = idx
+}
+
+// Rank returns the number of bits set to one before i.
+func (s *Set) Rank(i int) int {
+	if i <= 0 {
+		return 0
+	}
+	if i >= s.length {
+		return s.Count()
+	}
+
+	w := i >> log2Word
+	partial := bits.OnesCount64(s.data[w] & (1<<uint(i&(wordSize-1)) - 1))
+
+	if s.index == nil {
+		count := partial
+		for _, word := range s.data[:w] {
+			count += bits.OnesCount64(word)
+		}
+		return count
+	}
+
+	return s.index.wordRank(w) + partial
+}
+
+// Select returns the index of the k-th bit set to one, counting from zero.
+// The second return value is false if fewer than k+1 bits are set.
+func (s *Set) Select(k int) (int, bool) {
+	if k < 0 {
+		return -1, false
+	}
+
+	if s.index == nil {
+		for w, word := range s.data {
+			n := bits.OnesCount64(word)
+			if k < n {
+				return w<<log2Word + selectInWord(word, k), true
+			}
+			k -= n
+		}
+		return -1, false
+	}
+
+	counts := s.index.counts
+	if k >= counts[len(counts)-1] {
+		return -1, false
+	}
+
+	// Find the last block with fewer than k+1 bits set before it.
+	b := sort.Search(len(counts), func(b int) bool {
+		return counts[b] > k
+	}) - 1
+
+	w := b * blockWords
+	end := min(w+blockWords, len(s.data))
+	for w+1 < end && s.index.wordRank(w+1) <= k {
+		w++
+	}
+
+	return w<<log2Word + selectInWord(s.data[w], k-s.index.wordRank(w)), true
+}
+
+// wordRank returns the number of bits set before word w.
+func (idx *rankIndex) wordRank(w int) int {
+	b, j := w/blockWords, w%blockWords
+	rank := idx.counts[b]
+	if j > 0 {
+		rank += int(idx.relative[b]>>(9*uint(j-1))) & 0x1ff
+	}
+	return rank
+}
+
+// selectInWord returns the position of the k-th bit set to one in a word.
+func selectInWord(word uint64, k int) int {
+	for ; k > 0; k-- {
+		word &= word - 1
+	}
+	return bits.TrailingZeros64(word)
+}
@@ -0,0 +1,82 @@
+package bitset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRankSelect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	s := New(5000)
+	for i := 0; i < 1500; i++ {
+		s.Set(rnd.Intn(s.Size()))
+	}
+	set := s.AppendTo(nil)
+
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.BuildIndex()
+		}
+
+		var rank int
+		for i := 0; i <= s.Size(); i++ {
+			if got := s.Rank(i); got != rank {
+				t.Fatalf("indexed=%v: s.Rank(%d) = %d; want = %d", indexed, i, got, rank)
+			}
+			if s.Get(i) {
+				rank++
+			}
+		}
+
+		for k, want := range set {
+			if got, ok := s.Select(k); got != want || !ok {
+				t.Fatalf("indexed=%v: s.Select(%d) = %d, %v; want = %d, %v", indexed, k, got, ok, want, true)
+			}
+		}
+		if _, ok := s.Select(len(set)); ok {
+			t.Fatalf("indexed=%v: s.Select(%d) should fail", indexed, len(set))
+		}
+	}
+}
+
+func TestRankIndexInvalidated(t *testing.T) {
+	s := newSetWith(1000, 10, 500)
+	s.BuildIndex()
+
+	s.Set(5)
+	if s.index != nil {
+		t.Fatal("index was not dropped on modification")
+	}
+	if got := s.Rank(600); got != 3 {
+		t.Errorf("s.Rank() = %d; want = %d", got, 3)
+	}
+	if got, _ := s.Select(0); got != 5 {
+		t.Errorf("s.Select() = %d; want = %d", got, 5)
+	}
+}
+
+func BenchmarkRank(b *testing.B) {
+	s := New(benchSize)
+	for i := 0; i < s.Size(); i += 7 {
+		s.Set(i)
+	}
+	s.BuildIndex()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Rank(i % benchSize)
+	}
+}
+
+func BenchmarkSelect(b *testing.B) {
+	s := New(benchSize)
+	for i := 0; i < s.Size(); i += 7 {
+		s.Set(i)
+	}
+	s.BuildIndex()
+	n := s.Count()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Select(i % n)
+	}
+}
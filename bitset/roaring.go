@@ -0,0 +1,502 @@
+package bitset
+
+import (
+	"cmp"
+	"iter"
+	"math"
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+// Bitmap is the set of operations shared by the dense Set and the
+// compressed Roaring bitmap.
+type Bitmap interface {
+	Set(i int) error
+	Clear(i int) error
+	Get(i int) bool
+	Count() int
+	Any() bool
+	None() bool
+	Indices() iter.Seq[int]
+}
+
+var (
+	_ Bitmap = (*Set)(nil)
+	_ Bitmap = (*Roaring)(nil)
+)
+
+const (
+	// arrayMaxSize is the largest cardinality stored as an array container.
+	arrayMaxSize = 4096
+
+	// bitmapWords is the number of words in a bitmap container.
+	bitmapWords = 1 << 16 / wordSize
+)
+
+type containerKind uint8
+
+const (
+	arrayContainer containerKind = iota
+	bitmapContainer
+	runContainer
+)
+
+// run is an inclusive range of values in a run container.
+type run struct {
+	start, last uint16
+}
+
+// container holds the low 16 bits of the values in a chunk of 2^16 values.
+type container struct {
+	kind   containerKind
+	n      int
+	array  []uint16
+	bitmap []uint64
+	runs   []run
+}
+
+// Roaring is a compressed bitmap for indices in [0, 2^32). Values are split
+// into chunks of 2^16 by their high 16 bits, and each chunk is stored as a
+// sorted array, a bitmap or a list of runs depending on which is smaller.
+type Roaring struct {
+	keys       []uint16
+	containers []*container
+}
+
+// NewRoaring returns a new empty compressed bitmap.
+func NewRoaring() *Roaring {
+	return &Roaring{}
+}
+
+// Set sets a bit to 1.
+func (r *Roaring) Set(i int) error {
+	if i < 0 || uint64(i) > math.MaxUint32 {
+		return errOutOfRange
+	}
+
+	hi, lo := uint16(i>>16), uint16(i)
+
+	idx, ok := slices.BinarySearch(r.keys, hi)
+	if !ok {
+		r.keys = slices.Insert(r.keys, idx, hi)
+		r.containers = slices.Insert(r.containers, idx, &container{})
+	}
+	r.containers[idx].add(lo)
+
+	return nil
+}
+
+// Clear sets a bit to 0.
+func (r *Roaring) Clear(i int) error {
+	if i < 0 || uint64(i) > math.MaxUint32 {
+		return errOutOfRange
+	}
+
+	hi, lo := uint16(i>>16), uint16(i)
+
+	idx, ok := slices.BinarySearch(r.keys, hi)
+	if !ok {
+		return nil
+	}
+
+	c := r.containers[idx]
+	c.remove(lo)
+	if c.n == 0 {
+		r.keys = slices.Delete(r.keys, idx, idx+1)
+		r.containers = slices.Delete(r.containers, idx, idx+1)
+	}
+
+	return nil
+}
+
+// Get returns whether a bit is set or not.
+func (r *Roaring) Get(i int) bool {
+	if i < 0 || uint64(i) > math.MaxUint32 {
+		return false
+	}
+
+	idx, ok := slices.BinarySearch(r.keys, uint16(i>>16))
+	if !ok {
+		return false
+	}
+	return r.containers[idx].contains(uint16(i))
+}
+
+// Count returns the number of bits set to one.
+func (r *Roaring) Count() int {
+	var count int
+	for _, c := range r.containers {
+		count += c.n
+	}
+	return count
+}
+
+// Any tests whether any bit is set.
+func (r *Roaring) Any() bool {
+	return len(r.containers) > 0
+}
+
+// None tests if no bits are set.
+func (r *Roaring) None() bool {
+	return len(r.containers) == 0
+}
+
+// Indices returns an iterator over the indices of the bits set to one, in
+// increasing order.
+func (r *Roaring) Indices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for idx, c := range r.containers {
+			base := int(r.keys[idx]) << 16
+			for lo := range c.values() {
+				if !yield(base | int(lo)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// RunOptimize converts containers to run containers where that uses less
+// memory. Run containers are converted back on the next mutation.
+func (r *Roaring) RunOptimize() {
+	for _, c := range r.containers {
+		c.runOptimize()
+	}
+}
+
+// Clone returns a copy of the bitmap.
+func (r *Roaring) Clone() *Roaring {
+	res := &Roaring{
+		keys:       slices.Clone(r.keys),
+		containers: make([]*container, len(r.containers)),
+	}
+	for i, c := range r.containers {
+		res.containers[i] = c.clone()
+	}
+	return res
+}
+
+// Union returns a new bitmap with the bits set in either r or other.
+func (r *Roaring) Union(other *Roaring) *Roaring {
+	return r.combine(other, opOr)
+}
+
+// Intersection returns a new bitmap with the bits set in both r and other.
+func (r *Roaring) Intersection(other *Roaring) *Roaring {
+	return r.combine(other, opAnd)
+}
+
+// Difference returns a new bitmap with the bits set in r but not in other.
+func (r *Roaring) Difference(other *Roaring) *Roaring {
+	return r.combine(other, opAndNot)
+}
+
+// SymmetricDifference returns a new bitmap with the bits set in exactly one
+// of r and other.
+func (r *Roaring) SymmetricDifference(other *Roaring) *Roaring {
+	return r.combine(other, opXor)
+}
+
+type setOp uint8
+
+const (
+	opOr setOp = iota
+	opAnd
+	opAndNot
+	opXor
+)
+
+func (op setOp) word(a, b uint64) uint64 {
+	switch op {
+	case opOr:
+		return a | b
+	case opAnd:
+		return a & b
+	case opAndNot:
+		return a &^ b
+	default:
+		return a ^ b
+	}
+}
+
+func (op setOp) keep(a, b bool) bool {
+	switch op {
+	case opOr:
+		return a || b
+	case opAnd:
+		return a && b
+	case opAndNot:
+		return a && !b
+	default:
+		return a != b
+	}
+}
+
+// combine merges the containers of r and other by their keys.
+func (r *Roaring) combine(other *Roaring, op setOp) *Roaring {
+	res := &Roaring{}
+
+	add := func(key uint16, c *container) {
+		if c != nil && c.n > 0 {
+			res.keys = append(res.keys, key)
+			res.containers = append(res.containers, c)
+		}
+	}
+
+	var i, j int
+	for i < len(r.keys) || j < len(other.keys) {
+		switch {
+		case j == len(other.keys) || (i < len(r.keys) && r.keys[i] < other.keys[j]):
+			if op.keep(true, false) {
+				add(r.keys[i], r.containers[i].clone())
+			}
+			i++
+		case i == len(r.keys) || other.keys[j] < r.keys[i]:
+			if op.keep(false, true) {
+				add(other.keys[j], other.containers[j].clone())
+			}
+			j++
+		default:
+			add(r.keys[i], combineContainers(r.containers[i], other.containers[j], op))
+			i++
+			j++
+		}
+	}
+
+	return res
+}
+
+func combineContainers(a, b *container, op setOp) *container {
+	if a.kind == arrayContainer && b.kind == arrayContainer {
+		return fromArray(mergeSorted(a.array, b.array, op))
+	}
+
+	wa, wb := a.words(), b.words()
+	for i := range wa {
+		wa[i] = op.word(wa[i], wb[i])
+	}
+	return fromWords(wa)
+}
+
+// mergeSorted returns the sorted values kept by op from two sorted slices.
+func mergeSorted[T cmp.Ordered](a, b []T, op setOp) []T {
+	var res []T
+
+	var i, j int
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			if op.keep(true, false) {
+				res = append(res, a[i])
+			}
+			i++
+		case i == len(a) || b[j] < a[i]:
+			if op.keep(false, true) {
+				res = append(res, b[j])
+			}
+			j++
+		default:
+			if op.keep(true, true) {
+				res = append(res, a[i])
+			}
+			i++
+			j++
+		}
+	}
+
+	return res
+}
+
+// fromArray returns a container with the values of a sorted array.
+func fromArray(a []uint16) *container {
+	c := &container{kind: arrayContainer, n: len(a), array: a}
+	if c.n > arrayMaxSize {
+		c.toBitmap()
+	}
+	return c
+}
+
+// fromWords returns a container with the bits of a bitmap, using an array
+// if the cardinality is low enough.
+func fromWords(words []uint64) *container {
+	c := &container{kind: bitmapContainer, bitmap: words}
+	for _, w := range words {
+		c.n += bits.OnesCount64(w)
+	}
+	if c.n <= arrayMaxSize {
+		c.toArray()
+	}
+	return c
+}
+
+func (c *container) add(x uint16) {
+	if c.kind == runContainer {
+		c.unrun()
+	}
+
+	switch c.kind {
+	case arrayContainer:
+		idx, ok := slices.BinarySearch(c.array, x)
+		if ok {
+			return
+		}
+		if c.n < arrayMaxSize {
+			c.array = slices.Insert(c.array, idx, x)
+			c.n++
+			return
+		}
+		c.toBitmap()
+		fallthrough
+	case bitmapContainer:
+		mask := uint64(1) << (x & (wordSize - 1))
+		if c.bitmap[x>>log2Word]&mask == 0 {
+			c.bitmap[x>>log2Word] |= mask
+			c.n++
+		}
+	}
+}
+
+func (c *container) remove(x uint16) {
+	if c.kind == runContainer {
+		c.unrun()
+	}
+
+	switch c.kind {
+	case arrayContainer:
+		if idx, ok := slices.BinarySearch(c.array, x); ok {
+			c.array = slices.Delete(c.array, idx, idx+1)
+			c.n--
+		}
+	case bitmapContainer:
+		mask := uint64(1) << (x & (wordSize - 1))
+		if c.bitmap[x>>log2Word]&mask != 0 {
+			c.bitmap[x>>log2Word] &^= mask
+			c.n--
+		}
+		if c.n <= arrayMaxSize {
+			c.toArray()
+		}
+	}
+}
+
+func (c *container) contains(x uint16) bool {
+	switch c.kind {
+	case arrayContainer:
+		_, ok := slices.BinarySearch(c.array, x)
+		return ok
+	case bitmapContainer:
+		return c.bitmap[x>>log2Word]&(1<<(x&(wordSize-1))) != 0
+	default:
+		idx := sort.Search(len(c.runs), func(i int) bool {
+			return c.runs[i].last >= x
+		})
+		return idx < len(c.runs) && c.runs[idx].start <= x
+	}
+}
+
+// values returns an iterator over the values in the container, in
+// increasing order.
+func (c *container) values() iter.Seq[uint16] {
+	return func(yield func(uint16) bool) {
+		switch c.kind {
+		case arrayContainer:
+			for _, x := range c.array {
+				if !yield(x) {
+					return
+				}
+			}
+		case bitmapContainer:
+			for w, word := range c.bitmap {
+				for word != 0 {
+					if !yield(uint16(w<<log2Word + bits.TrailingZeros64(word))) {
+						return
+					}
+					word &= word - 1
+				}
+			}
+		default:
+			for _, r := range c.runs {
+				for x := int(r.start); x <= int(r.last); x++ {
+					if !yield(uint16(x)) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// words returns the contents of the container as a newly allocated bitmap.
+func (c *container) words() []uint64 {
+	if c.kind == bitmapContainer {
+		return slices.Clone(c.bitmap)
+	}
+
+	words := make([]uint64, bitmapWords)
+	for x := range c.values() {
+		words[x>>log2Word] |= 1 << (x & (wordSize - 1))
+	}
+	return words
+}
+
+func (c *container) toBitmap() {
+	c.bitmap = c.words()
+	c.array = nil
+	c.runs = nil
+	c.kind = bitmapContainer
+}
+
+func (c *container) toArray() {
+	c.array = slices.AppendSeq(make([]uint16, 0, c.n), c.values())
+	c.bitmap = nil
+	c.runs = nil
+	c.kind = arrayContainer
+}
+
+// unrun converts a run container to an array or bitmap container.
+func (c *container) unrun() {
+	if c.n <= arrayMaxSize {
+		c.toArray()
+	} else {
+		c.toBitmap()
+	}
+}
+
+// runOptimize converts the container to a run container if that is smaller
+// than its current representation.
+func (c *container) runOptimize() {
+	if c.kind == runContainer {
+		return
+	}
+
+	var runs []run
+	for x := range c.values() {
+		if n := len(runs); n > 0 && runs[n-1].last+1 == x {
+			runs[n-1].last = x
+		} else {
+			runs = append(runs, run{start: x, last: x})
+		}
+	}
+
+	size := 2 * c.n
+	if c.kind == bitmapContainer {
+		size = 8 * bitmapWords
+	}
+	if 4*len(runs) < size {
+		c.runs = runs
+		c.array = nil
+		c.bitmap = nil
+		c.kind = runContainer
+	}
+}
+
+func (c *container) clone() *container {
+	return &container{
+		kind:   c.kind,
+		n:      c.n,
+		array:  slices.Clone(c.array),
+		bitmap: slices.Clone(c.bitmap),
+		runs:   slices.Clone(c.runs),
+	}
+}
@@ -0,0 +1,164 @@
+package bitset
+
+import (
+	"math/bits"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestRoaringSetGet(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	r := NewRoaring()
+	want := make(map[int]bool)
+	for i := 0; i < 20000; i++ {
+		// Concentrate values in a few chunks so that both array and bitmap
+		// containers are used.
+		v := rnd.Intn(3)<<16 | rnd.Intn(1<<14)
+		if i%7 == 0 {
+			r.Clear(v)
+			delete(want, v)
+		} else {
+			r.Set(v)
+			want[v] = true
+		}
+	}
+
+	if r.Count() != len(want) {
+		t.Fatalf("r.Count() = %d; want = %d", r.Count(), len(want))
+	}
+	for v := range want {
+		if !r.Get(v) {
+			t.Fatalf("r.Get(%d) should return %v", v, true)
+		}
+	}
+
+	var prev = -1
+	for v := range r.Indices() {
+		if v <= prev || !want[v] {
+			t.Fatalf("unexpected index %d after %d", v, prev)
+		}
+		prev = v
+	}
+}
+
+func TestRoaringContainerConversion(t *testing.T) {
+	r := NewRoaring()
+	for i := 0; i <= arrayMaxSize; i++ {
+		r.Set(2 * i)
+	}
+	if r.containers[0].kind != bitmapContainer {
+		t.Fatalf("kind = %d; want = %d", r.containers[0].kind, bitmapContainer)
+	}
+
+	r.Clear(0)
+	if r.containers[0].kind != arrayContainer {
+		t.Fatalf("kind = %d; want = %d", r.containers[0].kind, arrayContainer)
+	}
+	if r.Count() != arrayMaxSize {
+		t.Fatalf("r.Count() = %d; want = %d", r.Count(), arrayMaxSize)
+	}
+
+	for i := 0; i < arrayMaxSize; i++ {
+		r.Clear(2 * (i + 1))
+	}
+	if r.Any() || len(r.containers) != 0 {
+		t.Fatal("r.Any() should return", false)
+	}
+}
+
+func TestRoaringRunOptimize(t *testing.T) {
+	r := NewRoaring()
+	for i := 1000; i < 60000; i++ {
+		r.Set(i)
+	}
+	r.Set(70000)
+
+	r.RunOptimize()
+
+	if r.containers[0].kind != runContainer {
+		t.Fatalf("kind = %d; want = %d", r.containers[0].kind, runContainer)
+	}
+	if r.Count() != 59001 {
+		t.Fatalf("r.Count() = %d; want = %d", r.Count(), 59001)
+	}
+	if !r.Get(1000) || !r.Get(59999) || r.Get(999) || r.Get(60000) {
+		t.Fatal("unexpected bits in run container")
+	}
+
+	r.Clear(5000)
+	if r.containers[0].kind == runContainer {
+		t.Fatal("run container was not converted on mutation")
+	}
+	if r.Get(5000) || r.Count() != 59000 {
+		t.Fatal("unexpected bits after mutation")
+	}
+}
+
+func TestRoaringAlgebra(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+
+	const n = 1 << 18
+	da, db := New(n), New(n)
+	ra, rb := NewRoaring(), NewRoaring()
+	for i := 0; i < 30000; i++ {
+		a, b := rnd.Intn(n), rnd.Intn(n/2)
+		da.Set(a)
+		ra.Set(a)
+		db.Set(b)
+		rb.Set(b)
+	}
+	rb.RunOptimize()
+
+	for _, tt := range []struct {
+		name string
+		got  *Roaring
+		want *Set
+	}{
+		{"Union", ra.Union(rb), da.Union(db)},
+		{"Intersection", ra.Intersection(rb), da.Intersection(db)},
+		{"Difference", ra.Difference(rb), da.Difference(db)},
+		{"SymmetricDifference", ra.SymmetricDifference(rb), da.SymmetricDifference(db)},
+	} {
+		got := slices.Collect(tt.got.Indices())
+		want := slices.Collect(tt.want.Indices())
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %d indices; want %d", tt.name, len(got), len(want))
+		}
+		if tt.got.Count() != len(want) {
+			t.Errorf("%s: Count() = %d; want = %d", tt.name, tt.got.Count(), len(want))
+		}
+	}
+}
+
+func TestRoaringOutOfRange(t *testing.T) {
+	r := NewRoaring()
+
+	if err := r.Set(-1); err != errOutOfRange {
+		t.Errorf("r.Set() = %v; want = %v", err, errOutOfRange)
+	}
+
+	// Indices beyond 32 bits only fit in an int on 64-bit platforms.
+	if bits.UintSize == 64 {
+		limit := uint64(1) << 32
+		if err := r.Set(int(limit)); err != errOutOfRange {
+			t.Errorf("r.Set() = %v; want = %v", err, errOutOfRange)
+		}
+		if err := r.Set(int(limit - 1)); err != nil {
+			t.Errorf("r.Set() = %v; want = %v", err, nil)
+		}
+	}
+}
+
+func TestBitmapInterface(t *testing.T) {
+	for _, b := range []Bitmap{New(100), NewRoaring()} {
+		b.Set(3)
+		b.Set(42)
+		b.Clear(3)
+
+		if b.Count() != 1 || !b.Get(42) || b.Get(3) || b.None() {
+			t.Errorf("%T: unexpected contents", b)
+		}
+	}
+}
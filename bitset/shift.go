@@ -0,0 +1,105 @@
+package bitset
+
+// ShiftLeft moves every bit n positions towards higher indices, so that bit
+// i ends up at i+n. Bits shifted beyond the end of the set are dropped and
+// the lowest n bits are cleared. A negative n shifts right.
+func (s *Set) ShiftLeft(n int) {
+	if n < 0 {
+		// Shifting by at least the size clears the set in either
+		// direction. Checking first also avoids negating math.MinInt.
+		if n > -s.length {
+			s.ShiftRight(-n)
+			return
+		}
+		n = s.length
+	}
+	if n == 0 {
+		return
+	}
+
+	s.modified(0, len(s.data))
+	if n >= s.length {
+		clear(s.data)
+		return
+	}
+
+	words, offset := n>>log2Word, uint(n&(wordSize-1))
+	for i := len(s.data) - 1; i >= words; i-- {
+		src := i - words
+		w := s.data[src] << offset
+		if offset > 0 && src > 0 {
+			w |= s.data[src-1] >> (wordSize - offset)
+		}
+		s.data[i] = w
+	}
+	clear(s.data[:words])
+	s.clearPadding()
+}
+
+// ShiftRight moves every bit n positions towards lower indices, so that bit
+// i ends up at i-n. Bits shifted below zero are dropped and the highest n
+// bits are cleared. A negative n shifts left.
+func (s *Set) ShiftRight(n int) {
+	if n < 0 {
+		// Shifting by at least the size clears the set in either
+		// direction. Checking first also avoids negating math.MinInt.
+		if n > -s.length {
+			s.ShiftLeft(-n)
+			return
+		}
+		n = s.length
+	}
+	if n == 0 {
+		return
+	}
+
+	s.modified(0, len(s.data))
+	if n >= s.length {
+		clear(s.data)
+		return
+	}
+
+	words, offset := n>>log2Word, uint(n&(wordSize-1))
+	last := len(s.data) - words
+	for i := 0; i < last; i++ {
+		src := i + words
+		w := s.data[src] >> offset
+		if offset > 0 && src+1 < len(s.data) {
+			w |= s.data[src+1] << (wordSize - offset)
+		}
+		s.data[i] = w
+	}
+	clear(s.data[last:])
+}
+
+// RotateLeft moves every bit n positions towards higher indices, wrapping
+// the bits shifted beyond the end around to the start of the set. A
+// negative n rotates right.
+func (s *Set) RotateLeft(n int) {
+	if s.length == 0 {
+		return
+	}
+
+	n %= s.length
+	if n < 0 {
+		n += s.length
+	}
+	if n == 0 {
+		return
+	}
+
+	wrapped := s.Clone()
+	wrapped.ShiftRight(s.length - n)
+	s.ShiftLeft(n)
+	s.InPlaceUnion(wrapped)
+}
+
+// RotateRight moves every bit n positions towards lower indices, wrapping
+// the bits shifted below zero around to the end of the set. A negative n
+// rotates left.
+func (s *Set) RotateRight(n int) {
+	if s.length == 0 {
+		return
+	}
+	s.RotateLeft(-(n % s.length))
+}
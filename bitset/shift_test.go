@@ -0,0 +1,132 @@
+package bitset
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// shifted returns the expected result of moving every bit in s by n
+// positions, optionally wrapping around.
+func shifted(s *Set, n int, rotate bool) *Set {
+	res := New(s.Size())
+	for i := 0; i < s.Size(); i++ {
+		if !s.Get(i) {
+			continue
+		}
+		j := i + n
+		if rotate {
+			j = ((j % s.Size()) + s.Size()) % s.Size()
+		}
+		res.Set(j)
+	}
+	return res
+}
+
+func TestShift(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{1, 63, 64, 65, 200, 256} {
+		src := New(size)
+		for i := 0; i < size; i++ {
+			if rnd.Intn(2) == 0 {
+				src.Set(i)
+			}
+		}
+
+		for _, n := range []int{0, 1, 7, 63, 64, 65, 130, size - 1, size, size + 3} {
+			s := src.Clone()
+			s.ShiftLeft(n)
+			if want := shifted(src, n, false); !s.Equal(want) {
+				t.Errorf("size %d: ShiftLeft(%d) = %s; want = %s", size, n, s, want)
+			}
+
+			s = src.Clone()
+			s.ShiftRight(n)
+			if want := shifted(src, -n, false); !s.Equal(want) {
+				t.Errorf("size %d: ShiftRight(%d) = %s; want = %s", size, n, s, want)
+			}
+
+			s = src.Clone()
+			s.RotateLeft(n)
+			if want := shifted(src, n, true); !s.Equal(want) {
+				t.Errorf("size %d: RotateLeft(%d) = %s; want = %s", size, n, s, want)
+			}
+
+			s = src.Clone()
+			s.RotateRight(n)
+			if want := shifted(src, -n, true); !s.Equal(want) {
+				t.Errorf("size %d: RotateRight(%d) = %s; want = %s", size, n, s, want)
+			}
+		}
+	}
+}
+
+func TestShiftNegative(t *testing.T) {
+	s := newSetWith(100, 10)
+
+	s.ShiftLeft(-3)
+	if !s.Get(7) || s.Count() != 1 {
+		t.Errorf("ShiftLeft(-3) = %v", indices(s))
+	}
+
+	s.RotateRight(-95)
+	if !s.Get(2) || s.Count() != 1 {
+		t.Errorf("RotateRight(-95) = %v", indices(s))
+	}
+}
+
+func TestShiftMinInt(t *testing.T) {
+	// None of these may recurse forever.
+	for _, shift := range []func(*Set, int){(*Set).ShiftLeft, (*Set).ShiftRight, (*Set).RotateLeft, (*Set).RotateRight} {
+		s := newSetWith(100, 10, 99)
+		shift(s, math.MinInt)
+		shift(s, math.MaxInt)
+	}
+
+	s := newSetWith(100, 10, 99)
+	s.ShiftLeft(math.MinInt)
+	if s.Any() {
+		t.Errorf("ShiftLeft(math.MinInt) = %v; want = []", indices(s))
+	}
+
+	s = newSetWith(100, 10, 99)
+	s.ShiftRight(math.MinInt)
+	if s.Any() {
+		t.Errorf("ShiftRight(math.MinInt) = %v; want = []", indices(s))
+	}
+}
+
+// TestBitap uses shifts to implement the shift-and string matching
+// algorithm.
+func TestBitap(t *testing.T) {
+	text, pattern := "the quick brown fox jumps over the lazy dog", "the"
+
+	masks := make(map[byte]*Set)
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if masks[c] == nil {
+			masks[c] = New(len(pattern))
+		}
+		masks[c].Set(i)
+	}
+
+	var matches []int
+	state := New(len(pattern))
+	for i := 0; i < len(text); i++ {
+		state.ShiftLeft(1)
+		state.Set(0)
+		if m := masks[text[i]]; m != nil {
+			state.InPlaceIntersection(m)
+		} else {
+			state.ClearRange(0, state.Size())
+		}
+		if state.Get(len(pattern) - 1) {
+			matches = append(matches, i-len(pattern)+1)
+		}
+	}
+
+	if want := []int{0, 31}; !equalInts(matches, want) {
+		t.Errorf("matches = %v; want = %v", matches, want)
+	}
+}
@@ -0,0 +1,211 @@
+package bitset
+
+import (
+	"iter"
+	"math/bits"
+	"slices"
+	"sync"
+)
+
+// chunkWords is the number of words in each chunk shared with snapshots.
+const chunkWords = 512
+
+// cowState tracks the chunks of a set that are shared with snapshots.
+// Snapshots read the words of the set directly, and a shared chunk is only
+// copied when the set is about to write to it.
+type cowState struct {
+	// mu is held for reading while a snapshot reads a chunk that may still
+	// be shared, and for writing while such a chunk is copied.
+	mu sync.RWMutex
+
+	// live holds the chunk that snapshots share with the set for each chunk
+	// index, or nil if the chunk is not shared.
+	live []*chunk
+}
+
+// chunk is a run of words read by snapshots. Its words alias those of the
+// set until the set writes to them, at which point they are replaced by a
+// copy.
+type chunk struct {
+	words []uint64
+}
+
+// touch copies the shared chunks holding the words in [lo, hi) of data, so
+// that data can be written to.
+func (c *cowState) touch(data []uint64, lo, hi int) {
+	if lo >= hi {
+		return
+	}
+
+	for i := lo / chunkWords; i <= (hi-1)/chunkWords && i < len(c.live); i++ {
+		ch := c.live[i]
+		if ch == nil {
+			continue
+		}
+
+		// Chunks of a backing array that the set no longer uses are
+		// never written again and can stay as they are.
+		if c.aliases(ch, data, i) {
+			c.mu.Lock()
+			ch.words = slices.Clone(ch.words)
+			c.mu.Unlock()
+		}
+		c.live[i] = nil
+	}
+}
+
+// aliases returns whether a chunk shares its words with the i-th chunk of
+// data.
+func (c *cowState) aliases(ch *chunk, data []uint64, i int) bool {
+	return i*chunkWords < len(data) && &ch.words[0] == &data[i*chunkWords]
+}
+
+// Snapshot returns an immutable view of the set as it is now. The snapshot
+// shares its words with the set, and each chunk of 512 words is copied only
+// when the set first writes to it afterwards, so taking a snapshot costs
+// little regardless of the size of the set.
+//
+// Snapshot must not be called concurrently with modifications of the set,
+// but the returned snapshot can be read from any number of goroutines while
+// the set is modified.
+func (s *Set) Snapshot() *Snapshot {
+	n := (len(s.data) + chunkWords - 1) / chunkWords
+
+	if s.cow == nil {
+		s.cow = &cowState{}
+	}
+	if len(s.cow.live) < n {
+		s.cow.live = append(s.cow.live, make([]*chunk, n-len(s.cow.live))...)
+	}
+
+	snap := &Snapshot{
+		cow:    s.cow,
+		chunks: make([]*chunk, n),
+		length: s.length,
+	}
+
+	for i := range snap.chunks {
+		ch := s.cow.live[i]
+
+		lo, hi := i*chunkWords, min((i+1)*chunkWords, len(s.data))
+		if ch != nil && s.cow.aliases(ch, s.data, i) && len(ch.words) != hi-lo {
+			// The last chunk has grown since it was shared, so the
+			// snapshots that hold it need a copy before it is replaced.
+			s.cow.touch(s.data, lo, hi)
+			ch = nil
+		}
+		if ch == nil || !s.cow.aliases(ch, s.data, i) {
+			ch = &chunk{words: s.data[lo:hi:hi]}
+			s.cow.live[i] = ch
+		}
+		snap.chunks[i] = ch
+	}
+
+	return snap
+}
+
+// Snapshot is an immutable view of a Set, as returned by Set.Snapshot. It
+// is safe for concurrent use.
+type Snapshot struct {
+	cow    *cowState
+	chunks []*chunk
+	length int
+}
+
+// read calls f with the words of each chunk, in order, while they can't be
+// changed. It stops when f returns false.
+func (s *Snapshot) read(f func(c int, words []uint64) bool) {
+	s.cow.mu.RLock()
+	defer s.cow.mu.RUnlock()
+
+	for c, ch := range s.chunks {
+		if !f(c, ch.words) {
+			return
+		}
+	}
+}
+
+// Get returns whether a bit is set or not.
+func (s *Snapshot) Get(i int) bool {
+	if i < 0 || i >= s.length {
+		return false
+	}
+	w := i >> log2Word
+
+	s.cow.mu.RLock()
+	defer s.cow.mu.RUnlock()
+
+	return s.chunks[w/chunkWords].words[w%chunkWords]&(1<<uint(i&(wordSize-1))) != 0
+}
+
+// Size returns the number of bits, both ones and zeroes.
+func (s *Snapshot) Size() int {
+	return s.length
+}
+
+// Count returns the number of bits set to one.
+func (s *Snapshot) Count() int {
+	var count int
+	s.read(func(_ int, words []uint64) bool {
+		for _, w := range words {
+			count += bits.OnesCount64(w)
+		}
+		return true
+	})
+	return count
+}
+
+// Any tests whether any bit is set.
+func (s *Snapshot) Any() bool {
+	var found bool
+	s.read(func(_ int, words []uint64) bool {
+		for _, w := range words {
+			if w != 0 {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// None tests if no bits are set.
+func (s *Snapshot) None() bool {
+	return !s.Any()
+}
+
+// Indices returns an iterator over the indices of the bits set to one, in
+// increasing order.
+func (s *Snapshot) Indices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		// Copy each chunk before yielding from it, so that the loop body
+		// may modify the set without waiting for the iteration to end.
+		buf := make([]uint64, chunkWords)
+		for c, ch := range s.chunks {
+			s.cow.mu.RLock()
+			words := buf[:copy(buf, ch.words)]
+			s.cow.mu.RUnlock()
+
+			for w, word := range words {
+				base := (c*chunkWords + w) << log2Word
+				for word != 0 {
+					if !yield(base + bits.TrailingZeros64(word)) {
+						return
+					}
+					word &= word - 1
+				}
+			}
+		}
+	}
+}
+
+// Set returns a mutable copy of the snapshot.
+func (s *Snapshot) Set() *Set {
+	res := New(s.length)
+	s.read(func(c int, words []uint64) bool {
+		copy(res.data[c*chunkWords:], words)
+		return true
+	})
+	return res
+}
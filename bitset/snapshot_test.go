@@ -0,0 +1,156 @@
+package bitset
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := New(100000)
+	s.Set(1)
+	s.Set(50000)
+
+	snap := s.Snapshot()
+
+	s.Set(2)
+	s.Clear(50000)
+	s.SetRange(90000, 90010)
+
+	if got, want := snap.Set().Ranges(), "1,50000"; got != want {
+		t.Errorf("snapshot = %s; want = %s", got, want)
+	}
+	if snap.Count() != 2 || !snap.Get(50000) || snap.Get(2) {
+		t.Error("snapshot was modified")
+	}
+	if got, want := s.Ranges(), "1-2,90000-90009"; got != want {
+		t.Errorf("set = %s; want = %s", got, want)
+	}
+
+	snap2 := s.Snapshot()
+	if !snap2.Set().Equal(s) {
+		t.Error("second snapshot differs from the set")
+	}
+	if snap.Count() != 2 {
+		t.Error("first snapshot was modified by the second")
+	}
+}
+
+func TestSnapshotSharesChunks(t *testing.T) {
+	s := New(4 * chunkWords * wordSize)
+	s.Set(0)
+
+	// Taking a snapshot copies nothing.
+	a := s.Snapshot()
+	for c := 0; c < 4; c++ {
+		if &a.chunks[c].words[0] != &s.data[c*chunkWords] {
+			t.Errorf("chunk %d is not shared with the set", c)
+		}
+	}
+
+	// Only the chunk that is written to is copied.
+	s.Set(3 * chunkWords * wordSize)
+	for c := 0; c < 3; c++ {
+		if &a.chunks[c].words[0] != &s.data[c*chunkWords] {
+			t.Errorf("chunk %d was copied", c)
+		}
+	}
+	if &a.chunks[3].words[0] == &s.data[3*chunkWords] {
+		t.Error("modified chunk was not copied")
+	}
+	if a.Get(3 * chunkWords * wordSize) {
+		t.Error("snapshot was modified")
+	}
+
+	// Snapshots share the chunks that haven't changed in between.
+	b := s.Snapshot()
+	for c := 0; c < 3; c++ {
+		if a.chunks[c] != b.chunks[c] {
+			t.Errorf("chunk %d is not shared between snapshots", c)
+		}
+	}
+	if a.chunks[3] == b.chunks[3] {
+		t.Error("modified chunk is shared between snapshots")
+	}
+}
+
+func TestSnapshotGrownChunk(t *testing.T) {
+	s := NewGrowable(wordSize)
+	s.Set(1)
+
+	a := s.Snapshot()
+	s.Set(2 * wordSize)
+	b := s.Snapshot()
+	s.Clear(1)
+
+	if !a.Get(1) || a.Size() != wordSize {
+		t.Error("first snapshot was modified")
+	}
+	if !b.Get(1) || !b.Get(2*wordSize) {
+		t.Error("second snapshot was modified")
+	}
+}
+
+func TestSnapshotConcurrentWriter(t *testing.T) {
+	s := New(8 * chunkWords * wordSize)
+	s.SetRange(0, s.Size()/2)
+
+	snap := s.Snapshot()
+	want := s.Size() / 2
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if n := snap.Count(); n != want {
+					t.Errorf("snap.Count() = %d; want = %d", n, want)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < s.Size(); i += 1000 {
+		s.Flip(i)
+	}
+	wg.Wait()
+}
+
+func TestSnapshotResized(t *testing.T) {
+	s := NewGrowable(10)
+	s.Set(1)
+
+	snap := s.Snapshot()
+	s.Set(100000)
+
+	if snap.Size() != 10 || snap.Count() != 1 {
+		t.Error("snapshot was modified")
+	}
+	if snap := s.Snapshot(); snap.Size() != 100001 || snap.Count() != 2 {
+		t.Errorf("snap.Size() = %d, snap.Count() = %d; want = %d, %d", snap.Size(), snap.Count(), 100001, 2)
+	}
+}
+
+func TestSnapshotConcurrentReaders(t *testing.T) {
+	s := New(1 << 16)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		s.Set(i * 1000)
+		snap := s.Snapshot()
+
+		wg.Add(1)
+		go func(want int) {
+			defer wg.Done()
+			var n int
+			for range snap.Indices() {
+				n++
+			}
+			if n != want {
+				t.Errorf("snapshot has %d bits; want = %d", n, want)
+			}
+		}(i + 1)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/marcusolsson/exp/swim"
+)
+
+var (
+	defaultPort     = "3001"
+	defaultInterval = 50 // ms
+)
+
+func main() {
+	var bindAddr, advertiseAddr, joinAddr, name string
+	var interval int
+
+	flag.StringVar(&bindAddr, "bind", "0.0.0.0:"+defaultPort, "")
+	flag.StringVar(&advertiseAddr, "advertise", "", "")
+	flag.StringVar(&joinAddr, "join", "", "")
+	flag.StringVar(&name, "name", "", "")
+	flag.IntVar(&interval, "interval", defaultInterval, "")
+	flag.Parse()
+
+	logger := log.New(os.Stdout, "swim: ", 0)
+
+	list, err := swim.Create(swim.Config{
+		Name:           name,
+		BindAddr:       bindAddr,
+		AdvertiseAddr:  advertiseAddr,
+		GossipInterval: time.Duration(interval) * time.Millisecond,
+		Logger:         logger,
+	})
+	if err != nil {
+		logger.Fatal("unable to start server")
+	}
+
+	if joinAddr != "" {
+		if _, err := list.Join(joinAddr); err != nil {
+			logger.Fatalf("unable to join %s", joinAddr)
+		}
+	}
+
+	logger.Println("listening on", bindAddr, "as", list.LocalNode().Address)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+
+	if err := list.Leave(time.Second); err != nil {
+		logger.Println(err)
+	}
+
+	if err := list.Shutdown(); err != nil {
+		logger.Fatal(err)
+	}
+}
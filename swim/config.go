@@ -0,0 +1,64 @@
+package swim
+
+import (
+	"log"
+	"time"
+)
+
+// Config holds the configuration of a Memberlist.
+type Config struct {
+	// Name is the name of the local node. Defaults to the advertised
+	// address.
+	Name string
+
+	// BindAddr is the address to listen on, such as "0.0.0.0:3001".
+	// Defaults to the address of Transport, if one is set.
+	BindAddr string
+
+	// AdvertiseAddr is the address other nodes reach the local node at.
+	// Defaults to the address of Transport. A NetTransport bound to all
+	// interfaces, such as on "0.0.0.0:3001", advertises the address of one
+	// of them. Set AdvertiseAddr if that address can't be reached, for
+	// example behind NAT.
+	AdvertiseAddr string
+
+	// Transport carries the messages between nodes. Defaults to a
+	// NetTransport on BindAddr.
+	Transport Transport
+
+	// GossipInterval is the time between protocol rounds. Defaults to
+	// 50 milliseconds.
+	GossipInterval time.Duration
+
+	// PushPullInterval is the time between full member list exchanges
+	// with a random member, which let members catch up on the updates
+	// they missed. Defaults to 20 gossip intervals.
+	PushPullInterval time.Duration
+
+	// ProbeTimeout is how long to wait for a member to answer a ping.
+	// Defaults to the gossip interval.
+	ProbeTimeout time.Duration
+
+	// SuspicionTimeout is how long a member that fails to answer stays
+	// suspected before it is declared failed, once enough other members
+	// have confirmed the suspicion. Defaults to five gossip intervals.
+	SuspicionTimeout time.Duration
+
+	// SuspicionMaxTimeout is how long an unconfirmed suspicion lasts.
+	// Defaults to six times the suspicion timeout.
+	SuspicionMaxTimeout time.Duration
+
+	// Events receives every change to the member list, such as members
+	// that joined, failed or left on purpose, in the order the local node
+	// saw them. Events are queued rather than dropped while the channel
+	// is not ready, so it should be read until Shutdown. Defaults to not
+	// reporting events.
+	Events chan<- Event
+
+	// Logger receives the protocol logs. Defaults to discarding them.
+	Logger *log.Logger
+}
+
+// DefaultGossipInterval is the gossip interval used unless one is
+// configured.
+const DefaultGossipInterval = 50 * time.Millisecond
@@ -0,0 +1,144 @@
+package swim
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// packetBuffer is the number of packets a MemTransport buffers before it
+// starts dropping them.
+const packetBuffer = 1024
+
+var (
+	errAddrInUse   = errors.New("address already in use")
+	errUnreachable = errors.New("address unreachable")
+)
+
+// MemNetwork connects in-memory transports, so that many nodes can run in
+// the same process without using real ports.
+type MemNetwork struct {
+	mu         sync.Mutex
+	transports map[string]*MemTransport
+}
+
+// NewMemNetwork returns a new, empty in-memory network.
+func NewMemNetwork() *MemNetwork {
+	return &MemNetwork{transports: make(map[string]*MemTransport)}
+}
+
+// NewTransport returns a transport attached to the network at addr.
+func (n *MemNetwork) NewTransport(addr string) (*MemTransport, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.transports[addr]; ok {
+		return nil, errAddrInUse
+	}
+
+	t := &MemTransport{
+		network:  n,
+		addr:     addr,
+		packetCh: make(chan *Packet, packetBuffer),
+		streamCh: make(chan net.Conn),
+		shutdown: make(chan struct{}),
+	}
+	n.transports[addr] = t
+
+	return t, nil
+}
+
+func (n *MemNetwork) lookup(addr string) (*MemTransport, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	t, ok := n.transports[addr]
+	return t, ok
+}
+
+// MemTransport is a Transport on a MemNetwork.
+type MemTransport struct {
+	network *MemNetwork
+	addr    string
+
+	packetCh chan *Packet
+	streamCh chan net.Conn
+	shutdown chan struct{}
+	once     sync.Once
+}
+
+// LocalAddr returns the address of the transport on its network.
+func (t *MemTransport) LocalAddr() string {
+	return t.addr
+}
+
+// WriteTo sends a packet to the transport at addr. Like UDP, packets to
+// unknown addresses or full buffers are silently dropped.
+func (t *MemTransport) WriteTo(b []byte, addr string) error {
+	peer, ok := t.network.lookup(addr)
+	if !ok {
+		return nil
+	}
+
+	p := &Packet{
+		Buf:  append([]byte(nil), b...),
+		From: t.addr,
+	}
+
+	select {
+	case peer.packetCh <- p:
+	default:
+	}
+
+	return nil
+}
+
+// PacketCh returns the channel of received packets.
+func (t *MemTransport) PacketCh() <-chan *Packet {
+	return t.packetCh
+}
+
+// DialTimeout opens a stream to the transport at addr.
+func (t *MemTransport) DialTimeout(addr string, timeout time.Duration) (net.Conn, error) {
+	peer, ok := t.network.lookup(addr)
+	if !ok {
+		return nil, errUnreachable
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	local, remote := net.Pipe()
+
+	select {
+	case peer.streamCh <- remote:
+		return local, nil
+	case <-peer.shutdown:
+	case <-expired:
+	}
+
+	local.Close()
+	remote.Close()
+
+	return nil, errUnreachable
+}
+
+// StreamCh returns the channel of streams opened by other transports.
+func (t *MemTransport) StreamCh() <-chan net.Conn {
+	return t.streamCh
+}
+
+// Shutdown detaches the transport from its network.
+func (t *MemTransport) Shutdown() error {
+	t.once.Do(func() {
+		t.network.mu.Lock()
+		delete(t.network.transports, t.addr)
+		t.network.mu.Unlock()
+
+		close(t.shutdown)
+	})
+	return nil
+}
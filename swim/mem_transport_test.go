@@ -0,0 +1,78 @@
+package swim
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestMemTransport(t *testing.T) {
+	t.Parallel()
+
+	network := NewMemNetwork()
+
+	t1, err := network.NewTransport("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer t1.Shutdown()
+
+	t2, err := network.NewTransport("second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer t2.Shutdown()
+
+	if _, err := network.NewTransport("first"); err != errAddrInUse {
+		t.Errorf("NewTransport() = %v; want = %v", err, errAddrInUse)
+	}
+
+	// Packets
+	if err := t1.WriteTo([]byte("ping"), "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case p := <-t2.PacketCh():
+		if string(p.Buf) != "ping" || p.From != "first" {
+			t.Errorf("packet = %q from %s; want = %q from %s", p.Buf, p.From, "ping", "first")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("packet was not received")
+	}
+
+	if err := t1.WriteTo([]byte("ping"), "unknown"); err != nil {
+		t.Errorf("WriteTo(unknown) = %v; want = %v", err, nil)
+	}
+
+	// Streams
+	go func() {
+		conn := <-t2.StreamCh()
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	conn, err := t1.DialTimeout("second", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("join")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "join" {
+		t.Errorf("stream = %q; want = %q", buf, "join")
+	}
+
+	// Shutdown
+	t2.Shutdown()
+
+	if _, err := t1.DialTimeout("second", time.Second); err != errUnreachable {
+		t.Errorf("DialTimeout() = %v; want = %v", err, errUnreachable)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package swim
 
 import (
 	"errors"
@@ -12,65 +12,230 @@ type EventType int
 const (
 	Joined EventType = iota
 	Failed
+	Suspected
+	Left
 )
 
+// State is the state of a member.
+type State int
+
+// Available member states, in order of precedence.
+const (
+	Alive State = iota
+	Suspect
+	Dead
+	Departed
+)
+
+// state returns the state a member is in after an event.
+func (t EventType) state() State {
+	switch t {
+	case Suspected:
+		return Suspect
+	case Failed:
+		return Dead
+	case Left:
+		return Departed
+	default:
+		return Alive
+	}
+}
+
+// Event is a change to the member list, as seen by the local node. Joined
+// is also reported when a member refutes a suspicion.
+type Event struct {
+	Type   EventType
+	Member Member
+}
+
 // Update represents a change to the member list.
 type Update struct {
 	Member Member
 	Type   EventType
 	Round  int8
+
+	// From is the address of the member that raised a suspicion, so that
+	// independent confirmations can be told apart.
+	From string
 }
 
 // Member is a node in a cluster.
 type Member struct {
 	Name    string
 	Address string
+
+	// Incarnation is increased by the member itself whenever it needs to
+	// refute a suspicion, so that newer updates about a member take
+	// precedence over older ones.
+	Incarnation uint64
 }
 
-// List contains the members of a cluster.
+// List contains the members of a cluster. Suspected members are still
+// part of Members until they are declared failed. Members that left on
+// purpose are kept apart from the ones that failed.
 type List struct {
-	Members map[string]Member
-	Failed  map[string]Member
-	Updates []Update
-	Rounds  int
+	Members   map[string]Member
+	Suspected map[string]Member
+	Failed    map[string]Member
+	Left      map[string]Member
+	Updates   []Update
+	Rounds    int
+
+	// notify is called with every change to the list, if set.
+	notify func(Event)
 }
 
 // NewList returns a new instance of a member list.
 func NewList(rounds int) *List {
 	return &List{
-		Members: make(map[string]Member),
-		Failed:  make(map[string]Member),
-		Updates: make([]Update, 0),
-		Rounds:  rounds,
+		Members:   make(map[string]Member),
+		Suspected: make(map[string]Member),
+		Failed:    make(map[string]Member),
+		Left:      make(map[string]Member),
+		Updates:   make([]Update, 0),
+		Rounds:    rounds,
 	}
 }
 
 // Add adds a member to the member list.
 func (l *List) Add(m Member) {
 	if _, ok := l.Members[m.Address]; !ok {
-		l.Members[m.Address] = m
-		l.Updates = append(l.Updates, Update{Member: m, Type: Joined})
-		delete(l.Failed, m.Address)
+		l.setAlive(m)
+	}
+}
+
+// setAlive adds or updates a member as alive.
+func (l *List) setAlive(m Member) {
+	l.Members[m.Address] = m
+	l.record(Update{Member: m, Type: Joined})
+	delete(l.Suspected, m.Address)
+	delete(l.Failed, m.Address)
+	delete(l.Left, m.Address)
+}
+
+// Suspect marks a member as suspected of having failed by the member at
+// address from. It returns false if the member is not alive, already
+// suspected, or known at a newer incarnation than m.
+func (l *List) Suspect(m Member, from string) bool {
+	if cur, ok := l.Members[m.Address]; !ok || cur.Incarnation > m.Incarnation {
+		return false
+	}
+	if sm, ok := l.Suspected[m.Address]; ok && sm.Incarnation >= m.Incarnation {
+		return false
+	}
+
+	if l.Suspected == nil {
+		l.Suspected = make(map[string]Member)
 	}
+	l.Members[m.Address] = m
+	l.Suspected[m.Address] = m
+	l.record(Update{Member: m, Type: Suspected, From: from})
+
+	return true
 }
 
 // Remove removes a member from the member list.
 func (l *List) Remove(m Member) {
 	if _, ok := l.Members[m.Address]; ok {
 		l.Failed[m.Address] = m
-		l.Updates = append(l.Updates, Update{Member: m, Type: Failed})
+		l.record(Update{Member: m, Type: Failed})
 		delete(l.Members, m.Address)
+		delete(l.Suspected, m.Address)
 	}
 }
 
-// Merge updates the member list with updates.
+// Leave removes a member that left the cluster on purpose. A member that
+// was declared failed before its departure was known is moved as well.
+func (l *List) Leave(m Member) {
+	_, alive := l.Members[m.Address]
+	_, failed := l.Failed[m.Address]
+	if !alive && !failed {
+		return
+	}
+
+	if l.Left == nil {
+		l.Left = make(map[string]Member)
+	}
+	l.Left[m.Address] = m
+	l.record(Update{Member: m, Type: Left})
+	delete(l.Members, m.Address)
+	delete(l.Suspected, m.Address)
+	delete(l.Failed, m.Address)
+}
+
+// all returns an update for every known member in its current state, for
+// passing the whole list to another node.
+func (l *List) all() []Update {
+	updates := make([]Update, 0, len(l.Members)+len(l.Failed)+len(l.Left))
+	for addr, m := range l.Members {
+		if sm, ok := l.Suspected[addr]; ok {
+			updates = append(updates, Update{Member: sm, Type: Suspected})
+		} else {
+			updates = append(updates, Update{Member: m, Type: Joined})
+		}
+	}
+	for _, m := range l.Failed {
+		updates = append(updates, Update{Member: m, Type: Failed})
+	}
+	for _, m := range l.Left {
+		updates = append(updates, Update{Member: m, Type: Left})
+	}
+	return updates
+}
+
+// record appends an update to gossip and reports the change.
+func (l *List) record(u Update) {
+	l.Updates = append(l.Updates, u)
+	if l.notify != nil {
+		l.notify(Event{Type: u.Type, Member: u.Member})
+	}
+}
+
+// State returns the state and incarnation of the member with the given
+// address. The last return value is false if the member is unknown.
+func (l *List) State(addr string) (State, uint64, bool) {
+	if m, ok := l.Left[addr]; ok {
+		return Departed, m.Incarnation, true
+	}
+	if m, ok := l.Failed[addr]; ok {
+		return Dead, m.Incarnation, true
+	}
+	if m, ok := l.Suspected[addr]; ok {
+		return Suspect, m.Incarnation, true
+	}
+	if m, ok := l.Members[addr]; ok {
+		return Alive, m.Incarnation, true
+	}
+	return Alive, 0, false
+}
+
+// Merge updates the member list with updates. An update about a known
+// member is only applied if it has a higher incarnation, or the same
+// incarnation and a state of higher precedence, than what is already known.
 func (l *List) Merge(updates []Update) {
 	for _, u := range updates {
+		if state, inc, ok := l.State(u.Member.Address); ok {
+			if u.Member.Incarnation < inc {
+				continue
+			}
+			if u.Member.Incarnation == inc && u.Type.state() <= state {
+				continue
+			}
+		}
+
 		switch u.Type {
 		case Joined:
-			l.Add(u.Member)
+			l.setAlive(u.Member)
+		case Suspected:
+			// A member may be suspected before we learn that it joined, or
+			// after we declared it failed at an older incarnation.
+			delete(l.Failed, u.Member.Address)
+			l.Members[u.Member.Address] = u.Member
+			l.Suspect(u.Member, u.From)
 		case Failed:
 			l.Remove(u.Member)
+		case Left:
+			l.Leave(u.Member)
 		}
 	}
 }
@@ -1,4 +1,4 @@
-package main
+package swim
 
 import "testing"
 
@@ -151,3 +151,201 @@ func TestMemberList_MergeAdded(t *testing.T) {
 		t.Errorf("l.Updates[0] = %v; want = %v", l.Updates[0], want)
 	}
 }
+
+func TestMemberList_Suspect(t *testing.T) {
+	mem := Member{
+		Name:    "test_name",
+		Address: "test_addr",
+	}
+
+	rounds := 3
+	l := NewList(rounds)
+
+	if l.Suspect(mem, "other_addr") {
+		t.Errorf("l.Suspect(unknown) = true; want = false")
+	}
+
+	l.Add(mem)
+
+	if !l.Suspect(mem, "other_addr") {
+		t.Errorf("l.Suspect(mem) = false; want = true")
+	}
+	if l.Suspect(mem, "other_addr") {
+		t.Errorf("l.Suspect(mem) = true; want = false for an already suspected member")
+	}
+
+	if len(l.Members) != 1 {
+		t.Errorf("len(l.Members) = %d; want = %d", len(l.Members), 1)
+	}
+	if len(l.Suspected) != 1 {
+		t.Errorf("len(l.Suspected) = %d; want = %d", len(l.Suspected), 1)
+	}
+	if len(l.Updates) != 2 {
+		t.Errorf("len(l.Updates) = %d; want = %d", len(l.Updates), 2)
+	}
+
+	want := Update{Member: mem, Round: 0, Type: Suspected, From: "other_addr"}
+	if l.Updates[1] != want {
+		t.Errorf("l.Updates[1] = %v; want = %v", l.Updates[1], want)
+	}
+
+	if state, _, _ := l.State(mem.Address); state != Suspect {
+		t.Errorf("l.State() = %v; want = %v", state, Suspect)
+	}
+
+	l.Remove(mem)
+
+	if len(l.Suspected) != 0 {
+		t.Errorf("len(l.Suspected) = %d; want = %d", len(l.Suspected), 0)
+	}
+	if state, _, _ := l.State(mem.Address); state != Dead {
+		t.Errorf("l.State() = %v; want = %v", state, Dead)
+	}
+}
+
+func TestMemberList_SuspectStale(t *testing.T) {
+	stale := Member{
+		Name:    "test_name",
+		Address: "test_addr",
+	}
+
+	l := NewList(3)
+	l.Add(stale)
+	l.Suspect(stale, "other_addr")
+
+	// The member refutes the suspicion while a probe with the old copy is
+	// still in flight.
+	refuted := stale
+	refuted.Incarnation = 1
+	l.Merge([]Update{{Member: refuted, Type: Joined}})
+
+	if l.Suspect(stale, "other_addr") {
+		t.Errorf("l.Suspect(stale) = true; want = false")
+	}
+	if state, inc, _ := l.State(stale.Address); state != Alive || inc != 1 {
+		t.Errorf("l.State() = %v, %d; want = %v, %d", state, inc, Alive, 1)
+	}
+
+	if !l.Suspect(refuted, "other_addr") {
+		t.Errorf("l.Suspect(refuted) = false; want = true")
+	}
+}
+
+func TestMemberList_MergePrecedence(t *testing.T) {
+	mem := func(inc uint64) Member {
+		return Member{Name: "test_name", Address: "test_addr", Incarnation: inc}
+	}
+
+	for _, tt := range []struct {
+		name        string
+		updates     []Update
+		state       State
+		incarnation uint64
+	}{
+		{
+			name:    "suspected overrides alive",
+			updates: []Update{{Member: mem(0), Type: Joined}, {Member: mem(0), Type: Suspected}},
+			state:   Suspect,
+		},
+		{
+			name:    "alive does not override suspected",
+			updates: []Update{{Member: mem(0), Type: Suspected}, {Member: mem(0), Type: Joined}},
+			state:   Suspect,
+		},
+		{
+			name:        "refuted with higher incarnation",
+			updates:     []Update{{Member: mem(0), Type: Suspected}, {Member: mem(1), Type: Joined}},
+			state:       Alive,
+			incarnation: 1,
+		},
+		{
+			name:        "stale suspicion is ignored",
+			updates:     []Update{{Member: mem(2), Type: Joined}, {Member: mem(1), Type: Suspected}},
+			state:       Alive,
+			incarnation: 2,
+		},
+		{
+			name:    "failed overrides suspected",
+			updates: []Update{{Member: mem(0), Type: Suspected}, {Member: mem(0), Type: Failed}},
+			state:   Dead,
+		},
+		{
+			name:    "failed is not revived by same incarnation",
+			updates: []Update{{Member: mem(0), Type: Joined}, {Member: mem(0), Type: Failed}, {Member: mem(0), Type: Joined}},
+			state:   Dead,
+		},
+		{
+			name:    "left overrides failed",
+			updates: []Update{{Member: mem(0), Type: Joined}, {Member: mem(0), Type: Failed}, {Member: mem(0), Type: Left}},
+			state:   Departed,
+		},
+		{
+			name:    "left is not revived by same incarnation",
+			updates: []Update{{Member: mem(0), Type: Joined}, {Member: mem(0), Type: Left}, {Member: mem(0), Type: Joined}},
+			state:   Departed,
+		},
+		{
+			name:        "failed is revived by higher incarnation",
+			updates:     []Update{{Member: mem(0), Type: Joined}, {Member: mem(0), Type: Failed}, {Member: mem(1), Type: Joined}},
+			state:       Alive,
+			incarnation: 1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewList(3)
+			l.Merge(tt.updates)
+
+			state, inc, ok := l.State("test_addr")
+			if !ok {
+				t.Fatal("missing member")
+			}
+			if state != tt.state {
+				t.Errorf("state = %v; want = %v", state, tt.state)
+			}
+			if inc != tt.incarnation {
+				t.Errorf("incarnation = %d; want = %d", inc, tt.incarnation)
+			}
+		})
+	}
+}
+
+func TestMemberList_Leave(t *testing.T) {
+	mem := Member{
+		Name:    "test_name",
+		Address: "test_addr",
+	}
+
+	rounds := 3
+	l := NewList(rounds)
+	l.Add(mem)
+	l.Suspect(mem, "other_addr")
+	l.Leave(mem)
+
+	if len(l.Members) != 0 {
+		t.Errorf("len(l.Members) = %d; want = %d", len(l.Members), 0)
+	}
+	if len(l.Suspected) != 0 {
+		t.Errorf("len(l.Suspected) = %d; want = %d", len(l.Suspected), 0)
+	}
+	if len(l.Failed) != 0 {
+		t.Errorf("len(l.Failed) = %d; want = %d", len(l.Failed), 0)
+	}
+	if len(l.Left) != 1 {
+		t.Errorf("len(l.Left) = %d; want = %d", len(l.Left), 1)
+	}
+
+	want := Update{Member: mem, Round: 0, Type: Left}
+	if l.Updates[2] != want {
+		t.Errorf("l.Updates[2] = %v; want = %v", l.Updates[2], want)
+	}
+
+	if state, _, _ := l.State(mem.Address); state != Departed {
+		t.Errorf("l.State() = %v; want = %v", state, Departed)
+	}
+
+	l.Add(mem)
+
+	if len(l.Left) != 0 {
+		t.Errorf("len(l.Left) = %d; want = %d", len(l.Left), 0)
+	}
+}
@@ -0,0 +1,131 @@
+package swim
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"time"
+)
+
+// Memberlist is the local node's view of a cluster.
+type Memberlist struct {
+	srv *Server
+}
+
+// Create starts a new node with the given configuration. The node is alone
+// in its cluster until Join is called.
+func Create(conf Config) (*Memberlist, error) {
+	if conf.BindAddr == "" && conf.Transport != nil {
+		conf.BindAddr = conf.Transport.LocalAddr()
+	}
+	if conf.BindAddr == "" {
+		return nil, errors.New("missing bind address")
+	}
+	if conf.Name == "" {
+		conf.Name = conf.BindAddr
+	}
+	if conf.GossipInterval <= 0 {
+		conf.GossipInterval = DefaultGossipInterval
+	}
+	if conf.Logger == nil {
+		conf.Logger = log.New(io.Discard, "", 0)
+	}
+
+	if conf.PushPullInterval <= 0 {
+		conf.PushPullInterval = pushPullMult * conf.GossipInterval
+	}
+	if conf.ProbeTimeout <= 0 {
+		conf.ProbeTimeout = conf.GossipInterval
+	}
+	if conf.SuspicionTimeout <= 0 {
+		conf.SuspicionTimeout = suspicionMult * conf.GossipInterval
+	}
+	if conf.SuspicionMaxTimeout <= 0 {
+		conf.SuspicionMaxTimeout = suspicionMaxMult * conf.SuspicionTimeout
+	}
+
+	srv := NewServer(conf.BindAddr, 0, conf.Logger)
+	srv.GossipInterval = conf.GossipInterval
+	srv.PushPullInterval = conf.PushPullInterval
+	srv.ProbeTimeout = conf.ProbeTimeout
+	srv.SuspicionTimeout = conf.SuspicionTimeout
+	srv.SuspicionMaxTimeout = conf.SuspicionMaxTimeout
+	srv.Self.Name = conf.Name
+	srv.AdvertiseAddr = conf.AdvertiseAddr
+	srv.Events = conf.Events
+	srv.Transport = conf.Transport
+
+	if err := srv.Start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := srv.Listen(); err != nil {
+			conf.Logger.Println(err)
+		}
+	}()
+
+	return &Memberlist{srv: srv}, nil
+}
+
+// Join contacts the nodes at the given addresses and joins their cluster.
+// It returns the number of nodes that were successfully contacted, and an
+// error if none of them could be.
+func (m *Memberlist) Join(addrs ...string) (int, error) {
+	var (
+		n       int
+		lastErr error
+	)
+	for _, addr := range addrs {
+		if err := m.srv.Join(addr); err != nil {
+			lastErr = err
+			continue
+		}
+		n++
+	}
+
+	if n == 0 && lastErr != nil {
+		return 0, lastErr
+	}
+
+	return n, nil
+}
+
+// Members returns the live members of the cluster, including the local
+// node and any suspected members, sorted by address.
+func (m *Memberlist) Members() []Member {
+	m.srv.mu.Lock()
+	members := make([]Member, 0, len(m.srv.Members.Members))
+	for _, mem := range m.srv.Members.Members {
+		members = append(members, mem)
+	}
+	m.srv.mu.Unlock()
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Address < members[j].Address
+	})
+
+	return members
+}
+
+// LocalNode returns the local node.
+func (m *Memberlist) LocalNode() Member {
+	m.srv.mu.Lock()
+	defer m.srv.mu.Unlock()
+
+	return m.srv.Self
+}
+
+// Leave announces that the local node leaves the cluster and waits up to
+// timeout for enough members to acknowledge it. Other members remove the
+// node right away rather than detecting it as failed. The node keeps
+// answering until Shutdown is called.
+func (m *Memberlist) Leave(timeout time.Duration) error {
+	return m.srv.Leave(timeout)
+}
+
+// Shutdown stops the local node and closes its listener.
+func (m *Memberlist) Shutdown() error {
+	return m.srv.Shutdown()
+}
@@ -0,0 +1,298 @@
+package swim
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemberlist(t *testing.T) {
+	t.Parallel()
+
+	network := NewMemNetwork()
+
+	first, err := Create(Config{Name: "first", Transport: newMemTransport(t, network, ":3000"), GossipInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Shutdown()
+
+	second, err := Create(Config{Name: "second", Transport: newMemTransport(t, network, ":3001"), GossipInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Shutdown()
+
+	if n, err := second.Join(":3000", ":3009"); n != 1 || err != nil {
+		t.Fatalf("second.Join() = %d, %v; want = %d, %v", n, err, 1, nil)
+	}
+
+	want := []Member{
+		{Name: "first", Address: ":3000"},
+		{Name: "second", Address: ":3001"},
+	}
+
+	for _, m := range []*Memberlist{first, second} {
+		got := m.Members()
+		if len(got) != len(want) {
+			t.Fatalf("%s: len(m.Members()) = %d; want = %d", m.LocalNode().Name, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: m.Members()[%d] = %v; want = %v", m.LocalNode().Name, i, got[i], want[i])
+			}
+		}
+	}
+
+	if got := second.LocalNode(); got != want[1] {
+		t.Errorf("second.LocalNode() = %v; want = %v", got, want[1])
+	}
+
+	if err := second.Leave(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := first.Members(); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("first.Members() = %v; want = %v", got, want[:1])
+	}
+}
+
+func TestMemberlistJoinSeveral(t *testing.T) {
+	t.Parallel()
+
+	network := NewMemNetwork()
+
+	create := func(addr string) *Memberlist {
+		m, err := Create(Config{Transport: newMemTransport(t, network, addr), GossipInterval: 10 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { m.Shutdown() })
+		return m
+	}
+
+	// Two separate clusters, one with two members.
+	create(":3000")
+	if _, err := create(":3001").Join(":3000"); err != nil {
+		t.Fatal(err)
+	}
+	create(":3002")
+
+	m := create(":3003")
+	if n, err := m.Join(":3000", ":3002"); n != 2 || err != nil {
+		t.Fatalf("m.Join() = %d, %v; want = %d, %v", n, err, 2, nil)
+	}
+
+	var got []string
+	for _, mem := range m.Members() {
+		got = append(got, mem.Address)
+	}
+	if want := []string{":3000", ":3001", ":3002", ":3003"}; !slices.Equal(got, want) {
+		t.Errorf("m.Members() = %v; want = %v", got, want)
+	}
+}
+
+func TestMemberlistJoinFailure(t *testing.T) {
+	t.Parallel()
+
+	network := NewMemNetwork()
+
+	m, err := Create(Config{Transport: newMemTransport(t, network, ":3000")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	if n, err := m.Join(":3009"); n != 0 || err == nil {
+		t.Errorf("m.Join() = %d, %v; want an error", n, err)
+	}
+	if m.LocalNode().Name != ":3000" {
+		t.Errorf("m.LocalNode().Name = %s; want = %s", m.LocalNode().Name, ":3000")
+	}
+}
+
+func TestCreateMissingBindAddr(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Create(Config{}); err == nil {
+		t.Error("Create() should fail without a bind address")
+	}
+}
+
+func TestCreateAdvertiseAddr(t *testing.T) {
+	t.Parallel()
+
+	network := NewMemNetwork()
+
+	list, err := Create(Config{Transport: newMemTransport(t, network, ":3000"), AdvertiseAddr: ":4000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Shutdown()
+
+	want := Member{Name: ":4000", Address: ":4000"}
+	if got := list.LocalNode(); got != want {
+		t.Errorf("list.LocalNode() = %v; want = %v", got, want)
+	}
+}
+
+func TestMemberlistEvents(t *testing.T) {
+	t.Parallel()
+
+	var (
+		network = NewMemNetwork()
+		events  = make(chan Event)
+	)
+
+	conf := func(name, addr string) Config {
+		return Config{
+			Name:             name,
+			Transport:        newMemTransport(t, network, addr),
+			GossipInterval:   10 * time.Millisecond,
+			SuspicionTimeout: 50 * time.Millisecond,
+		}
+	}
+
+	firstConf := conf("first", ":3000")
+	firstConf.Events = events
+	first, err := Create(firstConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Shutdown()
+
+	var others []*Memberlist
+	for _, c := range []Config{conf("leaving", ":3001"), conf("crashing", ":3002")} {
+		m, err := Create(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Shutdown()
+
+		if _, err := m.Join(":3000"); err != nil {
+			t.Fatal(err)
+		}
+		others = append(others, m)
+	}
+
+	leaving, crashing := others[0], others[1]
+
+	if err := leaving.Leave(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	leaving.Shutdown()
+	crashing.Shutdown()
+
+	// Wait until both have been removed, and check how each of them went.
+	got := make(map[string]EventType)
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case e := <-events:
+			if e.Type == Left || e.Type == Failed {
+				got[e.Member.Name] = e.Type
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+	}
+
+	if got["leaving"] != Left {
+		t.Errorf("leaving: event = %v; want = %v", got["leaving"], Left)
+	}
+	if got["crashing"] != Failed {
+		t.Errorf("crashing: event = %v; want = %v", got["crashing"], Failed)
+	}
+}
+
+func TestMemberlistShutdownConcurrently(t *testing.T) {
+	t.Parallel()
+
+	network := NewMemNetwork()
+
+	m, err := Create(Config{Transport: newMemTransport(t, network, ":3000")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Shutdown()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMemberlistEventsAfterJoin(t *testing.T) {
+	t.Parallel()
+
+	var (
+		network = NewMemNetwork()
+		events  = make(chan Event, 16)
+	)
+
+	create := func(name, addr string, events chan<- Event) *Memberlist {
+		m, err := Create(Config{
+			Name:             name,
+			Transport:        newMemTransport(t, network, addr),
+			GossipInterval:   10 * time.Millisecond,
+			SuspicionTimeout: 50 * time.Millisecond,
+			Events:           events,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { m.Shutdown() })
+		return m
+	}
+
+	create("seed", ":3000", nil)
+
+	// The node that reports events joins, rather than being joined.
+	if _, err := create("joining", ":3001", events).Join(":3000"); err != nil {
+		t.Fatal(err)
+	}
+
+	crashing := create("crashing", ":3002", nil)
+	if _, err := crashing.Join(":3000"); err != nil {
+		t.Fatal(err)
+	}
+	crashing.Shutdown()
+
+	want := []Event{
+		{Type: Joined, Member: Member{Name: "joining", Address: ":3001"}},
+		{Type: Joined, Member: Member{Name: "seed", Address: ":3000"}},
+		{Type: Joined, Member: Member{Name: "crashing", Address: ":3002"}},
+	}
+
+	timeout := time.After(5 * time.Second)
+	for len(want) > 0 {
+		select {
+		case e := <-events:
+			if e == want[0] {
+				want = want[1:]
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %v", want[0])
+		}
+	}
+
+	// The crashed node is eventually reported as failed.
+	for {
+		select {
+		case e := <-events:
+			if e.Member.Name != "crashing" || e.Type == Suspected {
+				continue
+			}
+			if e.Type != Failed {
+				t.Fatalf("crashing: event = %v; want = %v", e.Type, Failed)
+			}
+			return
+		case <-timeout:
+			t.Fatal("timed out waiting for the crashed node to fail")
+		}
+	}
+}
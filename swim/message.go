@@ -1,4 +1,4 @@
-package main
+package swim
 
 import (
 	"bytes"
@@ -11,8 +11,11 @@ type messageType uint8
 const (
 	joinType messageType = 1 << iota
 	joinResponseType
-	queryType
-	queryResponseType
+	pingType
+	pingReqType
+	ackType
+	nackType
+	pushPullType
 )
 
 type messageJoin struct {
@@ -24,15 +27,35 @@ type messageJoinResponse struct {
 	Members List
 }
 
-type messageQuery struct {
-	Name    string
+type messagePing struct {
+	SeqNo   uint32
+	From    string
 	Updates []Update
-	Data    []byte
 }
 
-type messageQueryResponse struct {
+// messagePingReq asks the receiver to ping Target and to answer From with
+// an ack or a nack for SeqNo.
+type messagePingReq struct {
+	SeqNo   uint32
+	From    string
+	Target  string
+	Updates []Update
+}
+
+type messageAck struct {
+	SeqNo   uint32
+	Updates []Update
+}
+
+// messageNack is sent by a ping-req relay that could not reach the target.
+type messageNack struct {
+	SeqNo uint32
+}
+
+// messagePushPull carries the full member list of its sender. The receiver
+// answers with its own.
+type messagePushPull struct {
 	Updates []Update
-	Ack     bool
 }
 
 func encodeMessage(t messageType, m interface{}) ([]byte, error) {
@@ -0,0 +1,211 @@
+package swim
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxPacketSize is the largest UDP packet that can be received.
+const maxPacketSize = 65536
+
+// NetTransport is a Transport that sends packets over UDP and streams over
+// TCP, both on the same address.
+type NetTransport struct {
+	addr string
+	tcp  net.Listener
+	udp  net.PacketConn
+
+	packetCh chan *Packet
+	streamCh chan net.Conn
+	shutdown chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewNetTransport returns a NetTransport listening on bindAddr. If the port
+// is 0, a free port is picked. If the host is unspecified, such as 0.0.0.0,
+// the transport listens on all interfaces and advertises the address of
+// one of them instead.
+func NewNetTransport(bindAddr string) (*NetTransport, error) {
+	host, port, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	tcp, err := net.Listen("tcp", bindAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the same port for UDP, in case the TCP port was picked for us.
+	if port == "0" {
+		port = strconv.Itoa(tcp.Addr().(*net.TCPAddr).Port)
+		bindAddr = net.JoinHostPort(host, port)
+	}
+
+	udp, err := net.ListenPacket("udp", bindAddr)
+	if err != nil {
+		tcp.Close()
+		return nil, err
+	}
+
+	advertise, err := advertiseHost(host)
+	if err != nil {
+		tcp.Close()
+		udp.Close()
+		return nil, err
+	}
+
+	t := &NetTransport{
+		addr:     net.JoinHostPort(advertise, port),
+		tcp:      tcp,
+		udp:      udp,
+		packetCh: make(chan *Packet),
+		streamCh: make(chan net.Conn),
+		shutdown: make(chan struct{}),
+	}
+
+	t.wg.Add(2)
+	go t.readPackets()
+	go t.acceptStreams()
+
+	return t, nil
+}
+
+// LocalAddr returns the address other nodes reach the transport at.
+func (t *NetTransport) LocalAddr() string {
+	return t.addr
+}
+
+// WriteTo sends a UDP packet to addr.
+func (t *NetTransport) WriteTo(b []byte, addr string) error {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.udp.WriteTo(b, udpAddr)
+	return err
+}
+
+// PacketCh returns the channel of received UDP packets.
+func (t *NetTransport) PacketCh() <-chan *Packet {
+	return t.packetCh
+}
+
+// DialTimeout opens a TCP connection to addr.
+func (t *NetTransport) DialTimeout(addr string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("tcp", addr, timeout)
+}
+
+// StreamCh returns the channel of accepted TCP connections.
+func (t *NetTransport) StreamCh() <-chan net.Conn {
+	return t.streamCh
+}
+
+// Shutdown closes the listeners and waits for the transport to stop.
+func (t *NetTransport) Shutdown() error {
+	var err error
+	t.once.Do(func() {
+		close(t.shutdown)
+		err = t.tcp.Close()
+		if uerr := t.udp.Close(); err == nil {
+			err = uerr
+		}
+		t.wg.Wait()
+	})
+	return err
+}
+
+func (t *NetTransport) readPackets() {
+	defer t.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := t.udp.ReadFrom(buf)
+		if err != nil {
+			if t.isShutdown() {
+				return
+			}
+			continue
+		}
+
+		p := &Packet{
+			Buf:  append([]byte(nil), buf[:n]...),
+			From: addr.String(),
+		}
+
+		select {
+		case t.packetCh <- p:
+		case <-t.shutdown:
+			return
+		}
+	}
+}
+
+func (t *NetTransport) acceptStreams() {
+	defer t.wg.Done()
+
+	for {
+		conn, err := t.tcp.Accept()
+		if err != nil {
+			if t.isShutdown() {
+				return
+			}
+
+			// Back off on errors such as running out of file
+			// descriptors.
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		select {
+		case t.streamCh <- conn:
+		case <-t.shutdown:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// advertiseHost returns the host that other nodes reach a transport bound
+// to host at. An unspecified host is replaced by the first global unicast
+// address of the same family, or by the loopback address if there is none.
+func advertiseHost(host string) (string, error) {
+	ip := net.ParseIP(host)
+	if host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return host, nil
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+
+	v4 := ip == nil || ip.To4() != nil
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || !ipnet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if (ipnet.IP.To4() != nil) == v4 {
+			return ipnet.IP.String(), nil
+		}
+	}
+
+	if v4 {
+		return "127.0.0.1", nil
+	}
+	return "::1", nil
+}
+
+func (t *NetTransport) isShutdown() bool {
+	select {
+	case <-t.shutdown:
+		return true
+	default:
+		return false
+	}
+}
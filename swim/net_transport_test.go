@@ -0,0 +1,136 @@
+package swim
+
+import (
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNetTransport(t *testing.T) {
+	t.Parallel()
+
+	var (
+		interval = 10
+		logger   = log.New(ioutil.Discard, "", 0)
+	)
+
+	// Let the transports pick free ports, so that the test can run in
+	// parallel with others.
+	srv1 := NewServer("127.0.0.1:0", interval, logger)
+	srv1.ProbeTimeout = time.Second
+	if err := srv1.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv1.Shutdown()
+
+	go func() {
+		if err := srv1.Listen(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	srv2 := NewServer("127.0.0.1:0", interval, logger)
+	srv2.ProbeTimeout = time.Second
+	if err := srv2.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv2.Shutdown()
+
+	go func() {
+		if err := srv2.Listen(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if srv1.Self.Address == "127.0.0.1:0" {
+		t.Fatalf("srv1.Self.Address = %s; want the picked port", srv1.Self.Address)
+	}
+
+	// Join over TCP.
+	if err := srv2.Join(srv1.Self.Address); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ping over UDP.
+	if err := srv2.Ping(srv1.Self.Address); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv1.Ping(srv2.Self.Address); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNetTransportUnspecified(t *testing.T) {
+	t.Parallel()
+
+	var (
+		interval = 10
+		logger   = log.New(ioutil.Discard, "", 0)
+	)
+
+	srv1 := NewServer("0.0.0.0:0", interval, logger)
+	srv1.ProbeTimeout = time.Second
+	if err := srv1.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv1.Shutdown()
+
+	go func() {
+		if err := srv1.Listen(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(srv1.Self.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() || port == "0" {
+		t.Fatalf("srv1.Self.Address = %s; want a concrete address", srv1.Self.Address)
+	}
+
+	srv2 := NewServer("127.0.0.1:0", interval, logger)
+	srv2.ProbeTimeout = time.Second
+	if err := srv2.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv2.Shutdown()
+
+	go func() {
+		if err := srv2.Listen(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// Pings to the advertised address reach srv1, rather than looping
+	// back to the sender.
+	if err := srv2.Join(srv1.Self.Address); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv2.Ping(srv1.Self.Address); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv1.Ping(srv2.Self.Address); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdvertiseHost(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "localhost", "::1"} {
+		if got, err := advertiseHost(host); got != host || err != nil {
+			t.Errorf("advertiseHost(%q) = %q, %v; want = %q, %v", host, got, err, host, nil)
+		}
+	}
+
+	for _, host := range []string{"", "0.0.0.0", "::"} {
+		got, err := advertiseHost(host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ip := net.ParseIP(got); ip == nil || ip.IsUnspecified() {
+			t.Errorf("advertiseHost(%q) = %q; want a concrete address", host, got)
+		}
+	}
+}
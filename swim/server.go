@@ -1,11 +1,13 @@
-package main
+package swim
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"log"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,192 +15,641 @@ import (
 type Server struct {
 	BindAddr string
 
+	// AdvertiseAddr is the address other nodes reach the local node at.
+	// Start uses the address of the transport if it is empty.
+	AdvertiseAddr string
+
+	// mu guards Members, Self, suspicions, health and pending.
+	mu      sync.Mutex
 	Members *List
 	Self    Member
 
 	GossipInterval time.Duration
 
-	listener net.Listener
+	// PushPullInterval is the time between full member list exchanges
+	// with a random member, which let members catch up on updates that
+	// they missed while those were gossiped.
+	PushPullInterval time.Duration
+
+	// ProbeTimeout is how long to wait for an ack to a ping.
+	ProbeTimeout time.Duration
+
+	// SuspicionTimeout is how long a member stays suspected before it is
+	// declared failed, unless it refutes the suspicion. An unconfirmed
+	// suspicion lasts SuspicionMaxTimeout, which shrinks towards
+	// SuspicionTimeout as other members confirm it.
+	SuspicionTimeout    time.Duration
+	SuspicionMaxTimeout time.Duration
+	suspicions          map[string]*suspicion
+
+	// leaving is set once the local node has announced its departure, so
+	// that it no longer refutes it.
+	leaving bool
+
+	// health is the local health score, from 0 (healthy) to maxHealth. It
+	// stretches probe intervals and timeouts when the local node itself is
+	// slow to answer.
+	health int
+
+	// Transport carries the messages to other nodes. Start creates a
+	// NetTransport on BindAddr unless one is set.
+	Transport Transport
+
+	// Events receives every change to the member list, if set. Start
+	// begins delivering them.
+	Events chan<- Event
+
+	// pending holds the events that have yet to be sent on Events, and
+	// eventReady signals that more were added.
+	pending    []Event
+	eventReady chan struct{}
+
+	// ackHandlers holds the channels waiting for an ack or a nack to the
+	// pings in flight, by sequence number.
+	ackMu       sync.Mutex
+	ackHandlers map[uint32]chan bool
+	seqNo       atomic.Uint32
+
+	// stopGossip is closed to stop the protocol rounds, and shutdown is
+	// closed when the server is shut down.
+	stopGossip chan struct{}
+	shutdown   chan struct{}
+	stopOnce   sync.Once
+	closeOnce  sync.Once
 
 	Logger *log.Logger
 }
 
+const (
+	// suspicionMult is the default suspicion timeout in gossip intervals.
+	suspicionMult = 5
+
+	// suspicionMaxMult is the default unconfirmed suspicion timeout in
+	// multiples of the suspicion timeout.
+	suspicionMaxMult = 6
+
+	// suspicionConfirmations is the number of independent confirmations
+	// needed to shrink a suspicion timeout to its minimum.
+	suspicionConfirmations = 3
+
+	// indirectChecks is the number of members asked to probe a member
+	// that does not answer.
+	indirectChecks = 3
+
+	// maxHealth is the highest local health score.
+	maxHealth = 8
+
+	// streamTimeout bounds the exchanges over streams, such as joins.
+	streamTimeout = 10 * time.Second
+
+	// pushPullMult is the default push/pull interval in gossip intervals.
+	pushPullMult = 20
+
+	// leaveAcks is the number of members that need to acknowledge a
+	// departure before Leave returns.
+	leaveAcks = 3
+)
+
+var (
+	errNack     = errors.New("nack received")
+	errNoAck    = errors.New("ack not received")
+	errShutdown = errors.New("server is shut down")
+	errNoPeers  = errors.New("departure was not acknowledged by enough members")
+)
+
 // NewServer returns a new instance of Server.
 func NewServer(bindAddr string, interval int, logger *log.Logger) *Server {
+	gossipInterval := time.Duration(interval) * time.Millisecond
+
 	return &Server{BindAddr: bindAddr,
-		Members:        NewList(2),
-		Self:           Member{Address: bindAddr},
-		GossipInterval: time.Duration(interval) * time.Millisecond,
-		Logger:         logger,
+		Members:             NewList(2),
+		Self:                Member{Name: bindAddr, Address: bindAddr},
+		GossipInterval:      gossipInterval,
+		ProbeTimeout:        gossipInterval,
+		PushPullInterval:    pushPullMult * gossipInterval,
+		SuspicionTimeout:    suspicionMult * gossipInterval,
+		SuspicionMaxTimeout: suspicionMaxMult * suspicionMult * gossipInterval,
+		suspicions:          make(map[string]*suspicion),
+		ackHandlers:         make(map[uint32]chan bool),
+		eventReady:          make(chan struct{}, 1),
+		stopGossip:          make(chan struct{}),
+		shutdown:            make(chan struct{}),
+		Logger:              logger,
 	}
 }
 
 // Start ...
 func (s *Server) Start() error {
-	l, err := net.Listen("tcp", s.BindAddr)
-	if err != nil {
-		return err
+	if s.Transport == nil {
+		t, err := NewNetTransport(s.BindAddr)
+		if err != nil {
+			return err
+		}
+		s.Transport = t
+	}
+
+	// Add myself to the local membership list, at the address that other
+	// nodes reach us at.
+	addr := s.AdvertiseAddr
+	if addr == "" {
+		addr = s.Transport.LocalAddr()
 	}
-	s.listener = l
 
-	// Add myself to the local membership list.
+	s.mu.Lock()
+	if s.Events != nil {
+		s.Members.notify = s.queueEvent
+		go s.deliverEvents()
+	}
+	if addr != s.Self.Address {
+		if s.Self.Name == s.Self.Address {
+			s.Self.Name = addr
+		}
+		s.Self.Address = addr
+	}
 	s.Members.Add(s.Self)
+	s.mu.Unlock()
 
 	// Start gossiping.
 	go s.gossip()
+	go s.pushPull()
 
 	return nil
 }
 
+// Shutdown stops gossiping and shuts down the transport, which makes Listen
+// return.
+func (s *Server) Shutdown() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.stopGossiping()
+		close(s.shutdown)
+
+		if s.Transport != nil {
+			err = s.Transport.Shutdown()
+		}
+	})
+	return err
+}
+
+// Leave stops gossiping and announces that the local node leaves the
+// cluster, so that other members remove it right away instead of detecting
+// it as failed. It returns once enough members have acknowledged the
+// departure, or an error if that doesn't happen within timeout. The node
+// keeps answering until Shutdown is called.
+func (s *Server) Leave(timeout time.Duration) error {
+	s.stopGossiping()
+
+	s.mu.Lock()
+	s.leaving = true
+	s.Members.Leave(s.Self)
+	peers, err := s.Members.Random(len(s.Members.Members), s.Self)
+	s.mu.Unlock()
+
+	// Alone in the cluster, there is no one to tell.
+	if err != nil {
+		return nil
+	}
+
+	// Ping the remaining members, which carries the departure along with
+	// the other updates.
+	acks := make(chan error, len(peers))
+	for _, m := range peers {
+		go func(m Member) {
+			acks <- s.Ping(m.Address)
+		}(m)
+	}
+
+	need := min(leaveAcks, len(peers))
+	expired := time.After(timeout)
+
+	for pending := len(peers); pending > 0; pending-- {
+		select {
+		case err := <-acks:
+			if err == nil {
+				need--
+			}
+		case <-expired:
+			return errNoPeers
+		}
+
+		if need == 0 {
+			return nil
+		}
+	}
+
+	return errNoPeers
+}
+
+// stopGossiping stops the protocol rounds.
+func (s *Server) stopGossiping() {
+	s.stopOnce.Do(func() {
+		close(s.stopGossip)
+	})
+}
+
+// queueEvent queues an event for delivery on Events. It requires mu to be
+// held.
+func (s *Server) queueEvent(e Event) {
+	s.pending = append(s.pending, e)
+
+	select {
+	case s.eventReady <- struct{}{}:
+	default:
+	}
+}
+
+// deliverEvents sends the queued events on Events in order, until the
+// server is shut down. Queueing them keeps a slow reader from holding up
+// the protocol.
+func (s *Server) deliverEvents() {
+	for {
+		select {
+		case <-s.eventReady:
+		case <-s.shutdown:
+			return
+		}
+
+		s.mu.Lock()
+		events := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+
+		for _, e := range events {
+			select {
+			case s.Events <- e:
+			case <-s.shutdown:
+				return
+			}
+		}
+	}
+}
+
 // Join ...
 func (s *Server) Join(addr string) error {
 	if addr == "" {
 		return errors.New("missing address")
 	}
 
+	s.mu.Lock()
 	msg := messageJoin{
-		// TODO(marcusolsson): Find better member name.
-		Name:    s.BindAddr,
-		Address: s.BindAddr,
+		Name:    s.Self.Name,
+		Address: s.Self.Address,
 	}
+	s.mu.Unlock()
 
 	// Send join message.
-	resp, err := sendJoin(addr, msg)
+	resp, err := s.sendJoin(addr, msg)
 	if err != nil {
 		return err
 	}
 
-	// Initialize local membership list.
-	clone := resp.Members
-	s.Members = &clone
+	// Merge the members it knows about into our own list, so that joining
+	// several nodes adds up what each of them knows.
+	s.merge(resp.Members.all())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The node we joined may have bumped our incarnation to override an
+	// earlier failure.
+	if m, ok := s.Members.Members[s.Self.Address]; ok && m.Incarnation > s.Self.Incarnation {
+		s.Self.Incarnation = m.Incarnation
+	}
 
 	return nil
 }
 
-// Ping ...
+// Ping pings the member at addr and waits for its ack.
 func (s *Server) Ping(addr string) error {
-	msg := messageQuery{
-		Name:    "ping",
-		Updates: s.Members.Updates,
+	seq, ch := s.registerAck()
+	defer s.unregisterAck(seq)
+
+	msg := messagePing{
+		SeqNo:   seq,
+		From:    s.localAddr(),
+		Updates: s.updates(),
 	}
 
-	// Send ping message query.
-	resp, err := sendQuery(addr, msg)
-	if err != nil {
+	if err := s.sendPacket(addr, pingType, msg); err != nil {
 		return err
 	}
 
-	// Add the events received from the node.
-	s.Members.Merge(resp.Updates)
-
-	return nil
+	return s.waitAck(ch, s.scaled(s.ProbeTimeout))
 }
 
-// PingReq ...
+// PingReq asks m to ping target on our behalf. It returns errNack if m
+// could not reach target either.
 func (s *Server) PingReq(m Member, target Member) error {
-	msg := messageQuery{
-		Name:    "ping-req",
-		Updates: s.Members.Updates,
-		Data:    []byte(target.Address),
+	seq, ch := s.registerAck()
+	defer s.unregisterAck(seq)
+
+	msg := messagePingReq{
+		SeqNo:   seq,
+		From:    s.localAddr(),
+		Target:  target.Address,
+		Updates: s.updates(),
 	}
 
-	// Send ping-req query.
-	resp, err := sendQuery(m.Address, msg)
-	if err != nil {
+	if err := s.sendPacket(m.Address, pingReqType, msg); err != nil {
 		return err
 	}
 
-	if !resp.Ack {
-		return errors.New("ack not received")
+	// The relay needs time for its own probe.
+	return s.waitAck(ch, 2*s.scaled(s.ProbeTimeout))
+}
+
+// registerAck returns a new sequence number and the channel that receives
+// whether the ack for it was a nack.
+func (s *Server) registerAck() (uint32, chan bool) {
+	seq := s.seqNo.Add(1)
+	ch := make(chan bool, 1)
+
+	s.ackMu.Lock()
+	s.ackHandlers[seq] = ch
+	s.ackMu.Unlock()
+
+	return seq, ch
+}
+
+func (s *Server) unregisterAck(seq uint32) {
+	s.ackMu.Lock()
+	delete(s.ackHandlers, seq)
+	s.ackMu.Unlock()
+}
+
+// deliverAck passes an ack or a nack to the ping waiting for it, if any.
+func (s *Server) deliverAck(seq uint32, nack bool) {
+	s.ackMu.Lock()
+	ch, ok := s.ackHandlers[seq]
+	s.ackMu.Unlock()
+
+	if !ok {
+		return
 	}
 
-	// Add the events received from the node.
-	s.Members.Merge(resp.Updates)
+	select {
+	case ch <- nack:
+	default:
+	}
+}
 
-	return nil
+func (s *Server) waitAck(ch chan bool, timeout time.Duration) error {
+	select {
+	case nack := <-ch:
+		if nack {
+			return errNack
+		}
+		return nil
+	case <-time.After(timeout):
+		return errNoAck
+	case <-s.shutdown:
+		return errShutdown
+	}
+}
+
+// Health returns the local health score. Zero means healthy, and higher
+// scores stretch probe intervals and timeouts proportionally.
+func (s *Server) Health() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.health
+}
+
+// adjustHealth changes the local health score by delta. It must be called
+// with mu held.
+func (s *Server) adjustHealth(delta int) {
+	s.health = min(max(s.health+delta, 0), maxHealth)
+}
+
+// scaled returns d stretched by the local health score.
+func (s *Server) scaled(d time.Duration) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return d * time.Duration(s.health+1)
 }
 
-// Listen listens for incoming connections.
+// Listen handles incoming messages until the server is shut down.
 func (s *Server) Listen() error {
 	for {
-		conn, err := s.listener.Accept()
-		if err != nil {
-			return err
+		select {
+		case p := <-s.Transport.PacketCh():
+			if err := s.handlePacket(p); err != nil {
+				s.Logger.Println(err)
+			}
+		case conn := <-s.Transport.StreamCh():
+			go func() {
+				if err := s.handleStream(conn); err != nil {
+					s.Logger.Println(err)
+				}
+				conn.Close()
+			}()
+		case <-s.shutdown:
+			return nil
 		}
+	}
+}
 
-		// Read first byte to determine message type.
-		buf := make([]byte, 1)
-		if _, err := conn.Read(buf); err != nil {
+func (s *Server) handlePacket(p *Packet) error {
+	if len(p.Buf) == 0 {
+		return errors.New("empty packet")
+	}
+
+	r := bytes.NewReader(p.Buf[1:])
+
+	switch messageType(p.Buf[0]) {
+	case pingType:
+		var m messagePing
+		if err := decodeMessage(r, &m); err != nil {
+			return err
+		}
+		s.handlePing(m)
+	case pingReqType:
+		var m messagePingReq
+		if err := decodeMessage(r, &m); err != nil {
+			return err
+		}
+		// Probing the target needs the packets to keep flowing.
+		go s.handlePingReq(m)
+	case ackType:
+		var m messageAck
+		if err := decodeMessage(r, &m); err != nil {
+			return err
+		}
+		s.merge(m.Updates)
+		s.deliverAck(m.SeqNo, false)
+	case nackType:
+		var m messageNack
+		if err := decodeMessage(r, &m); err != nil {
 			return err
 		}
+		s.deliverAck(m.SeqNo, true)
+	default:
+		return errors.New("unrecognized message type")
+	}
 
-		switch messageType(buf[0]) {
-		case joinType:
-			var m messageJoin
-			if err := decodeMessage(conn, &m); err != nil {
-				return err
-			}
-			s.handleJoin(conn, m)
-		case queryType:
-			var m messageQuery
-			if err := decodeMessage(conn, &m); err != nil {
-				return err
-			}
-			switch m.Name {
-			case "ping":
-				s.handlePing(conn, m)
-			case "ping-req":
-				s.handlePingReq(conn, m)
-			}
-		case queryResponseType:
-			var m messageQueryResponse
-			if err := decodeMessage(conn, &m); err != nil {
-				return err
-			}
-		default:
-			s.Logger.Println("unrecognized message type")
+	return nil
+}
+
+func (s *Server) handleStream(conn net.Conn) error {
+	conn.SetDeadline(time.Now().Add(streamTimeout))
+
+	// Read first byte to determine message type.
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil {
+		return err
+	}
+
+	switch messageType(buf[0]) {
+	case joinType:
+		var m messageJoin
+		if err := decodeMessage(conn, &m); err != nil {
+			return err
 		}
+		s.handleJoin(conn, m)
+	case pushPullType:
+		var m messagePushPull
+		if err := decodeMessage(conn, &m); err != nil {
+			return err
+		}
+		s.handlePushPull(conn, m)
+	default:
+		return errors.New("unrecognized message type")
 	}
+
+	return nil
 }
 
 // gossip runs the SWIM protocol.
 func (s *Server) gossip() {
 	for {
-		<-time.After(s.GossipInterval)
+		select {
+		case <-s.stopGossip:
+			return
+		case <-time.After(s.scaled(s.GossipInterval)):
+		}
 
-		// Increase round number
+		// Increase round number and select one random node to ping.
+		s.mu.Lock()
 		s.Members.IncrementRound()
-
-		// Select one random node to ping.
 		m, err := s.Members.Random(1, s.Self)
+		s.mu.Unlock()
 		if err != nil {
 			continue
 		}
 
-		node := m[0]
+		s.probe(m[0])
+		s.expireSuspicions()
+	}
+}
 
-		if err := s.Ping(node.Address); err != nil {
-			s.Logger.Println("ping: failed to ping", node.Address)
+// probe pings a member, directly at first and then through other members,
+// and suspects it if no ack arrives. The local health score is lowered on
+// success and raised when the failure looks like our own fault.
+func (s *Server) probe(node Member) {
+	if err := s.Ping(node.Address); err == nil {
+		s.mu.Lock()
+		s.adjustHealth(-1)
+		s.mu.Unlock()
+		return
+	}
 
-			k := 3
-			randmem, err := s.Members.Random(k, s.Self, node)
-			if err != nil {
-				s.Logger.Println(err)
-			}
+	s.Logger.Println("ping: failed to ping", node.Address)
 
-			var ok bool
-			for err := range s.sendPingReq(node, randmem) {
-				if err == nil {
-					ok = true
-					break
-				}
-			}
+	s.mu.Lock()
+	randmem, err := s.Members.Random(indirectChecks, s.Self, node)
+	s.mu.Unlock()
+	if err != nil {
+		s.Logger.Println(err)
+	}
 
-			if !ok {
-				s.Logger.Println("ping-req: ack was not received, removing node", node.Address)
+	var ok bool
+	var nacks int
+	for err := range s.sendPingReq(node, randmem) {
+		if err == nil {
+			ok = true
+		} else if err == errNack {
+			nacks++
+		}
+	}
 
-				s.Members.Remove(node)
-			}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ok {
+		return
+	}
+
+	// Relays that don't answer at all suggest that we are the ones
+	// having problems. Without any relays, blame ourselves as well.
+	if len(randmem) == 0 {
+		s.adjustHealth(1)
+	} else {
+		s.adjustHealth(len(randmem) - nacks)
+	}
+
+	s.Logger.Println("ping-req: ack was not received, suspecting node", node.Address)
+
+	if !s.Members.Suspect(node, s.Self.Address) {
+		// The member is already suspected, most likely by someone else.
+		// Our failed probe independently confirms it, so gossip that
+		// once for the others to shorten their timeouts as well.
+		sus, ok := s.suspicions[node.Address]
+		if !ok || sus.incarnation != node.Incarnation {
+			return
+		}
+		if _, ok := sus.from[s.Self.Address]; ok {
+			return
+		}
+		s.Members.Updates = append(s.Members.Updates, Update{Member: s.Members.Suspected[node.Address], Type: Suspected, From: s.Self.Address})
+	}
+
+	s.reconcile()
+	s.suspicions[node.Address].confirm(s.Self.Address)
+}
+
+// expireSuspicions declares the members that have been suspected for longer
+// than the suspicion timeout as failed.
+func (s *Server) expireSuspicions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Smaller clusters can't provide as many confirmations.
+	k := min(suspicionConfirmations, len(s.Members.Members)-2)
+
+	for addr, sus := range s.suspicions {
+		if time.Since(sus.start) < sus.timeout(s.SuspicionTimeout, s.SuspicionMaxTimeout, k) {
+			continue
+		}
+
+		s.Logger.Println("suspicion timed out, removing node", addr)
+
+		s.Members.Remove(s.Members.Suspected[addr])
+		delete(s.suspicions, addr)
+	}
+}
+
+// reconcile refutes any suspicion about the local node and keeps the
+// suspicion timers in line with the member list. It must be called with mu
+// held.
+func (s *Server) reconcile() {
+	if state, inc, ok := s.Members.State(s.Self.Address); ok && state != Alive && !s.leaving {
+		s.Self.Incarnation = inc + 1
+		s.Members.setAlive(s.Self)
+
+		// Being suspected suggests that we are slow to answer.
+		s.adjustHealth(1)
+	}
+
+	for addr, m := range s.Members.Suspected {
+		if sus, ok := s.suspicions[addr]; !ok || sus.incarnation != m.Incarnation {
+			s.suspicions[addr] = newSuspicion(m.Incarnation)
+		}
+	}
+	for addr := range s.suspicions {
+		if _, ok := s.Members.Suspected[addr]; !ok {
+			delete(s.suspicions, addr)
 		}
 	}
 }
@@ -224,106 +675,197 @@ func (s *Server) sendPingReq(node Member, members []Member) <-chan error {
 	return ch
 }
 
-func (s *Server) handleJoin(w io.Writer, req messageJoin) {
-	s.Members.Add(Member{
-		Name:    req.Name,
-		Address: req.Address,
-	})
+// pushPull periodically exchanges the full member list with a random
+// member, until gossip stops.
+func (s *Server) pushPull() {
+	if s.PushPullInterval <= 0 {
+		return
+	}
 
-	s.Logger.Printf("join: member %s", req.Address)
+	for {
+		select {
+		case <-s.stopGossip:
+			return
+		case <-time.After(s.scaled(s.PushPullInterval)):
+		}
 
-	b, err := encodeMessage(joinResponseType, messageJoinResponse{Members: *s.Members})
-	if err != nil {
-		s.Logger.Println(err)
+		s.mu.Lock()
+		m, err := s.Members.Random(1, s.Self)
+		s.mu.Unlock()
+		if err != nil {
+			continue
+		}
+
+		if err := s.sendPushPull(m[0].Address); err != nil {
+			s.Logger.Println("push-pull:", err)
+		}
 	}
+}
 
-	w.Write(b)
+// sendPushPull sends the full member list to the member at addr, and merges
+// the one it answers with.
+func (s *Server) sendPushPull(addr string) error {
+	s.mu.Lock()
+	msg := messagePushPull{Updates: s.Members.all()}
+	s.mu.Unlock()
+
+	var resp messagePushPull
+	if err := s.exchange(addr, pushPullType, msg, pushPullType, &resp); err != nil {
+		return err
+	}
+
+	s.merge(resp.Updates)
+
+	return nil
 }
 
-func (s *Server) handlePing(w io.Writer, req messageQuery) {
-	s.Members.Merge(req.Updates)
+func (s *Server) handlePushPull(w io.Writer, req messagePushPull) {
+	s.mu.Lock()
+	b, err := encodeMessage(pushPullType, messagePushPull{Updates: s.Members.all()})
+	s.mu.Unlock()
 
-	b, err := encodeMessage(queryResponseType, messageQueryResponse{Updates: s.Members.Updates})
 	if err != nil {
 		s.Logger.Println(err)
+		return
 	}
-
 	w.Write(b)
+
+	s.merge(req.Updates)
 }
 
-func (s *Server) handlePingReq(w io.Writer, req messageQuery) {
-	s.Members.Merge(req.Updates)
+func (s *Server) handleJoin(w io.Writer, req messageJoin) {
+	m := Member{
+		Name:    req.Name,
+		Address: req.Address,
+	}
 
-	ack := true
-	if err := s.Ping(string(req.Data)); err != nil {
-		ack = false
+	s.mu.Lock()
+	// A member that rejoins needs a higher incarnation than before for the
+	// rest of the cluster to accept it.
+	if state, inc, ok := s.Members.State(m.Address); ok && state != Alive {
+		m.Incarnation = inc + 1
+		s.Members.setAlive(m)
+		s.reconcile()
+	} else {
+		s.Members.Add(m)
 	}
+	b, err := encodeMessage(joinResponseType, messageJoinResponse{Members: *s.Members})
+	s.mu.Unlock()
+
+	s.Logger.Printf("join: member %s", req.Address)
 
-	b, err := encodeMessage(queryResponseType, messageQueryResponse{Updates: s.Members.Updates, Ack: ack})
 	if err != nil {
 		s.Logger.Println(err)
-		return
 	}
 
 	w.Write(b)
 }
 
-func sendJoin(addr string, msg messageJoin) (messageJoinResponse, error) {
-	var resp messageJoinResponse
+func (s *Server) handlePing(req messagePing) {
+	s.merge(req.Updates)
 
-	c, err := NewClient(addr)
-	if err != nil {
-		return resp, err
+	ack := messageAck{SeqNo: req.SeqNo, Updates: s.updates()}
+	if err := s.sendPacket(req.From, ackType, ack); err != nil {
+		s.Logger.Println(err)
 	}
-	defer c.Close()
+}
 
-	r, err := c.sendMessage(joinType, msg)
-	if err != nil {
-		return resp, err
-	}
+func (s *Server) handlePingReq(req messagePingReq) {
+	s.merge(req.Updates)
 
-	buf := make([]byte, 1)
-	if _, err := r.Read(buf); err != nil {
-		return resp, err
+	if err := s.Ping(req.Target); err != nil {
+		// Send a nack rather than staying silent, so that the requester
+		// knows that we are reachable even though the target is not.
+		if err := s.sendPacket(req.From, nackType, messageNack{SeqNo: req.SeqNo}); err != nil {
+			s.Logger.Println(err)
+		}
+		return
 	}
 
-	if messageType(buf[0]) != joinResponseType {
-		return resp, err
+	ack := messageAck{SeqNo: req.SeqNo, Updates: s.updates()}
+	if err := s.sendPacket(req.From, ackType, ack); err != nil {
+		s.Logger.Println(err)
 	}
+}
 
-	if err := decodeMessage(r, &resp); err != nil {
-		return resp, err
+// sendPacket encodes a message and sends it as a packet to addr.
+func (s *Server) sendPacket(addr string, t messageType, msg interface{}) error {
+	b, err := encodeMessage(t, msg)
+	if err != nil {
+		return err
 	}
 
-	return resp, nil
+	return s.Transport.WriteTo(b, addr)
 }
 
-func sendQuery(addr string, q messageQuery) (messageQueryResponse, error) {
-	var response messageQueryResponse
+// localAddr returns the address other nodes reach us at.
+func (s *Server) localAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	c, err := NewClient(addr)
-	if err != nil {
-		return response, err
+	return s.Self.Address
+}
+
+// updates returns a copy of the pending updates to gossip.
+func (s *Server) updates() []Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Update(nil), s.Members.Updates...)
+}
+
+// merge applies updates received from another node.
+func (s *Server) merge(updates []Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Members.Merge(updates)
+	s.reconcile()
+
+	// Count suspicions raised by other members as confirmations.
+	for _, u := range updates {
+		if u.Type != Suspected || u.From == s.Self.Address {
+			continue
+		}
+		if sus, ok := s.suspicions[u.Member.Address]; ok && sus.incarnation == u.Member.Incarnation {
+			sus.confirm(u.From)
+		}
 	}
+}
+
+func (s *Server) sendJoin(addr string, msg messageJoin) (messageJoinResponse, error) {
+	var resp messageJoinResponse
+	err := s.exchange(addr, joinType, msg, joinResponseType, &resp)
+	return resp, err
+}
 
-	// Send query to node.
-	body, err := c.sendMessage(queryType, q)
+// exchange sends a message of type t over a new stream to addr, and decodes
+// the answer, which must be of type respType, into resp.
+func (s *Server) exchange(addr string, t messageType, msg interface{}, respType messageType, resp interface{}) error {
+	conn, err := s.Transport.DialTimeout(addr, streamTimeout)
 	if err != nil {
-		return response, err
+		return err
 	}
+	defer conn.Close()
 
-	buf := make([]byte, 1)
-	if _, err := body.Read(buf); err != nil {
-		return response, err
+	conn.SetDeadline(time.Now().Add(streamTimeout))
+
+	b, err := encodeMessage(t, msg)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(b); err != nil {
+		return err
 	}
 
-	if messageType(buf[0]) != queryResponseType {
-		return response, errors.New("unrecognized message type")
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil {
+		return err
 	}
 
-	if err := decodeMessage(body, &response); err != nil {
-		return response, err
+	if messageType(buf[0]) != respType {
+		return errors.New("unrecognized message type")
 	}
 
-	return response, nil
+	return decodeMessage(conn, resp)
 }
@@ -1,36 +1,42 @@
-package main
+package swim
 
 import (
 	"io/ioutil"
 	"log"
 	"testing"
+	"time"
 )
 
 func TestJoin(t *testing.T) {
+	t.Parallel()
+
 	var (
 		clientAddr = ":3001"
 		serverAddr = ":3000"
 		interval   = 10
+		network    = NewMemNetwork()
 		logger     = log.New(ioutil.Discard, "", 0)
 	)
 
 	srv1 := NewServer(serverAddr, interval, logger)
+	srv1.Transport = newMemTransport(t, network, serverAddr)
 	if err := srv1.Start(); err != nil {
 		t.Fatal(err)
 	}
-	defer srv1.listener.Close()
+	defer srv1.Shutdown()
 
 	go func() {
 		if err := srv1.Listen(); err != nil {
-			t.Fatal(err)
+			t.Error(err)
 		}
 	}()
 
 	srv2 := NewServer(clientAddr, interval, logger)
+	srv2.Transport = newMemTransport(t, network, clientAddr)
 	if err := srv2.Start(); err != nil {
 		t.Fatal(err)
 	}
-	defer srv2.listener.Close()
+	defer srv2.Shutdown()
 
 	if err := srv2.Join(serverAddr); err != nil {
 		t.Fatal(err)
@@ -64,41 +70,47 @@ func TestJoin(t *testing.T) {
 }
 
 func TestJoinThird(t *testing.T) {
+	t.Parallel()
+
 	var (
 		serverAddr       = ":3000"
 		firstClientAddr  = ":3001"
 		secondClientAddr = ":3002"
 		interval         = 10
+		network          = NewMemNetwork()
 		logger           = log.New(ioutil.Discard, "", 0)
 	)
 
 	srv1 := NewServer(serverAddr, interval, logger)
+	srv1.Transport = newMemTransport(t, network, serverAddr)
 	if err := srv1.Start(); err != nil {
 		t.Fatal(err)
 	}
-	defer srv1.listener.Close()
+	defer srv1.Shutdown()
 
 	go func() {
 		if err := srv1.Listen(); err != nil {
-			t.Fatal(err)
+			t.Error(err)
 		}
 	}()
 
 	srv2 := NewServer(firstClientAddr, interval, logger)
+	srv2.Transport = newMemTransport(t, network, firstClientAddr)
 	if err := srv2.Start(); err != nil {
 		t.Fatal(err)
 	}
-	defer srv2.listener.Close()
+	defer srv2.Shutdown()
 
 	if err := srv2.Join(serverAddr); err != nil {
 		t.Fatal(err)
 	}
 
 	srv3 := NewServer(secondClientAddr, interval, logger)
+	srv3.Transport = newMemTransport(t, network, secondClientAddr)
 	if err := srv3.Start(); err != nil {
 		t.Fatal(err)
 	}
-	defer srv3.listener.Close()
+	defer srv3.Shutdown()
 
 	if err := srv3.Join(serverAddr); err != nil {
 		t.Fatal(err)
@@ -151,31 +163,42 @@ func TestJoinThird(t *testing.T) {
 }
 
 func TestPing(t *testing.T) {
+	t.Parallel()
+
 	var (
 		serverAddr      = ":3000"
 		firstClientAddr = ":3001"
 		interval        = 10
+		network         = NewMemNetwork()
 		logger          = log.New(ioutil.Discard, "", 0)
 	)
 
 	srv1 := NewServer(serverAddr, interval, logger)
+	srv1.Transport = newMemTransport(t, network, serverAddr)
 
 	if err := srv1.Start(); err != nil {
 		t.Fatal(err)
 	}
-	defer srv1.listener.Close()
+	defer srv1.Shutdown()
 
 	go func() {
 		if err := srv1.Listen(); err != nil {
-			t.Fatal(err)
+			t.Error(err)
 		}
 	}()
 
 	srv2 := NewServer(firstClientAddr, interval, logger)
+	srv2.Transport = newMemTransport(t, network, firstClientAddr)
 	if err := srv2.Start(); err != nil {
 		t.Fatal(err)
 	}
-	defer srv2.listener.Close()
+	defer srv2.Shutdown()
+
+	go func() {
+		if err := srv2.Listen(); err != nil {
+			t.Error(err)
+		}
+	}()
 
 	if err := srv2.Join(serverAddr); err != nil {
 		t.Fatal(err)
@@ -218,3 +241,412 @@ func TestPing(t *testing.T) {
 	}
 
 }
+
+func TestRefuteSuspicion(t *testing.T) {
+	t.Parallel()
+
+	var (
+		serverAddr = ":3000"
+		interval   = 10
+		logger     = log.New(ioutil.Discard, "", 0)
+	)
+
+	srv := NewServer(serverAddr, interval, logger)
+	srv.Members.Add(srv.Self)
+
+	srv.merge([]Update{{Member: srv.Self, Type: Suspected}})
+
+	if srv.Self.Incarnation != 1 {
+		t.Errorf("srv.Self.Incarnation = %d; want = %d", srv.Self.Incarnation, 1)
+	}
+	if state, inc, _ := srv.Members.State(serverAddr); state != Alive || inc != 1 {
+		t.Errorf("srv.Members.State() = %v, %d; want = %v, %d", state, inc, Alive, 1)
+	}
+
+	want := Update{Member: srv.Self, Type: Joined}
+	if got := srv.Members.Updates[len(srv.Members.Updates)-1]; got != want {
+		t.Errorf("last update = %v; want = %v", got, want)
+	}
+}
+
+func TestSuspicionTimeout(t *testing.T) {
+	t.Parallel()
+
+	var (
+		serverAddr = ":3000"
+		memberAddr = ":3001"
+		interval   = 10
+		logger     = log.New(ioutil.Discard, "", 0)
+	)
+
+	srv := NewServer(serverAddr, interval, logger)
+	srv.SuspicionTimeout = time.Hour
+
+	mem := Member{Name: memberAddr, Address: memberAddr}
+	srv.Members.Add(mem)
+	srv.merge([]Update{{Member: mem, Type: Suspected}})
+
+	srv.expireSuspicions()
+
+	if state, _, _ := srv.Members.State(memberAddr); state != Suspect {
+		t.Errorf("state = %v; want = %v", state, Suspect)
+	}
+
+	srv.SuspicionTimeout = 0
+	srv.expireSuspicions()
+
+	if state, _, _ := srv.Members.State(memberAddr); state != Dead {
+		t.Errorf("state = %v; want = %v", state, Dead)
+	}
+	if len(srv.suspicions) != 0 {
+		t.Errorf("len(srv.suspicions) = %d; want = %d", len(srv.suspicions), 0)
+	}
+}
+
+func TestProbeHealth(t *testing.T) {
+	t.Parallel()
+
+	var (
+		serverAddr = ":3000"
+		clientAddr = ":3001"
+		deadAddr   = ":3003"
+		interval   = 10
+		network    = NewMemNetwork()
+		logger     = log.New(ioutil.Discard, "", 0)
+	)
+
+	srv1 := NewServer(serverAddr, interval, logger)
+	srv1.Transport = newMemTransport(t, network, serverAddr)
+	if err := srv1.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv1.Shutdown()
+
+	go func() {
+		if err := srv1.Listen(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// Listen without starting, so that no gossip interferes with the
+	// probes below.
+	srv2 := NewServer(clientAddr, interval, logger)
+	srv2.Transport = newMemTransport(t, network, clientAddr)
+	srv2.Members.Add(srv2.Self)
+	defer srv2.Shutdown()
+
+	go func() {
+		if err := srv2.Listen(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	dead := Member{Name: deadAddr, Address: deadAddr}
+	srv2.Members.Add(dead)
+
+	// Without any relays, a failed probe lowers our own health.
+	srv2.probe(dead)
+
+	if got := srv2.Health(); got != 1 {
+		t.Errorf("srv2.Health() = %d; want = %d", got, 1)
+	}
+	if state, _, _ := srv2.Members.State(deadAddr); state != Suspect {
+		t.Errorf("state = %v; want = %v", state, Suspect)
+	}
+
+	// A relay that answers with a nack shows that the target is at fault.
+	relay := Member{Name: serverAddr, Address: serverAddr}
+	srv2.Members.Add(relay)
+
+	if err := srv2.PingReq(relay, dead); err != errNack {
+		t.Errorf("srv2.PingReq() = %v; want = %v", err, errNack)
+	}
+
+	srv2.probe(dead)
+
+	if got := srv2.Health(); got != 1 {
+		t.Errorf("srv2.Health() = %d; want = %d", got, 1)
+	}
+
+	// Successful probes restore our health.
+	srv2.probe(relay)
+
+	if got := srv2.Health(); got != 0 {
+		t.Errorf("srv2.Health() = %d; want = %d", got, 0)
+	}
+}
+
+func TestSuspicionConfirmations(t *testing.T) {
+	t.Parallel()
+
+	var (
+		serverAddr = ":3000"
+		interval   = 10
+		logger     = log.New(ioutil.Discard, "", 0)
+	)
+
+	srv := NewServer(serverAddr, interval, logger)
+	srv.Members.Add(srv.Self)
+
+	mem := Member{Name: ":3001", Address: ":3001"}
+	srv.Members.Add(mem)
+	for _, addr := range []string{":3002", ":3003", ":3004"} {
+		srv.Members.Add(Member{Name: addr, Address: addr})
+	}
+
+	srv.merge([]Update{{Member: mem, Type: Suspected, From: ":3002"}})
+	srv.merge([]Update{{Member: mem, Type: Suspected, From: ":3003"}})
+	srv.merge([]Update{{Member: mem, Type: Suspected, From: ":3003"}})
+
+	if got := srv.suspicions[mem.Address].confirmations(); got != 1 {
+		t.Errorf("confirmations() = %d; want = %d", got, 1)
+	}
+
+	// A refuted suspicion starts over.
+	mem.Incarnation = 1
+	srv.merge([]Update{{Member: mem, Type: Joined}})
+	srv.merge([]Update{{Member: mem, Type: Suspected, From: ":3004"}})
+
+	if got := srv.suspicions[mem.Address].confirmations(); got != 0 {
+		t.Errorf("confirmations() = %d; want = %d", got, 0)
+	}
+}
+
+func TestSuspicionConfirmationsGossiped(t *testing.T) {
+	t.Parallel()
+
+	var (
+		addrs    = []string{":3000", ":3001", ":3002", ":3003"}
+		deadAddr = ":3004"
+		interval = 10
+		network  = NewMemNetwork()
+		logger   = log.New(ioutil.Discard, "", 0)
+	)
+
+	dead := Member{Name: deadAddr, Address: deadAddr}
+
+	// Listen without starting, so that gossip only spreads through the
+	// merges below.
+	var srvs []*Server
+	for _, addr := range addrs {
+		srv := NewServer(addr, interval, logger)
+		srv.Transport = newMemTransport(t, network, addr)
+		srv.SuspicionTimeout = time.Second
+		srv.SuspicionMaxTimeout = time.Minute
+		defer srv.Shutdown()
+
+		for _, addr := range addrs {
+			srv.Members.Add(Member{Name: addr, Address: addr})
+		}
+		srv.Members.Add(dead)
+
+		go func() {
+			if err := srv.Listen(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		srvs = append(srvs, srv)
+	}
+
+	timeout := func(srv *Server) time.Duration {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+
+		k := min(suspicionConfirmations, len(srv.Members.Members)-2)
+		return srv.suspicions[deadAddr].timeout(srv.SuspicionTimeout, srv.SuspicionMaxTimeout, k)
+	}
+
+	srvs[0].probe(dead)
+	if got := timeout(srvs[0]); got != time.Minute {
+		t.Fatalf("timeout = %v; want = %v", got, time.Minute)
+	}
+
+	// The others learn about the suspicion before their own probes fail,
+	// which must still count as confirmations.
+	prev := time.Minute
+	for _, srv := range srvs[1:] {
+		srv.merge(srvs[0].updates())
+		srv.probe(dead)
+		srvs[0].merge(srv.updates())
+
+		got := timeout(srvs[0])
+		if got >= prev {
+			t.Errorf("timeout = %v; want < %v", got, prev)
+		}
+		prev = got
+	}
+
+	if got := srvs[0].suspicions[deadAddr].confirmations(); got != 3 {
+		t.Errorf("confirmations() = %d; want = %d", got, 3)
+	}
+	if got := timeout(srvs[0]); got != time.Second {
+		t.Errorf("timeout = %v; want = %v", got, time.Second)
+	}
+
+	// Probing again doesn't gossip the confirmation twice.
+	n := len(srvs[1].updates())
+	srvs[1].probe(dead)
+	if got := len(srvs[1].updates()); got != n {
+		t.Errorf("len(updates) = %d; want = %d", got, n)
+	}
+}
+
+func TestPushPull(t *testing.T) {
+	t.Parallel()
+
+	var (
+		serverAddr = ":3000"
+		clientAddr = ":3001"
+		interval   = 10
+		network    = NewMemNetwork()
+		logger     = log.New(ioutil.Discard, "", 0)
+	)
+
+	// Listen without starting, so that only the exchange below spreads
+	// the member lists.
+	var srvs []*Server
+	for _, addr := range []string{serverAddr, clientAddr} {
+		srv := NewServer(addr, interval, logger)
+		srv.Transport = newMemTransport(t, network, addr)
+		srv.Members.Add(srv.Self)
+		defer srv.Shutdown()
+
+		go func() {
+			if err := srv.Listen(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		srvs = append(srvs, srv)
+	}
+
+	// Each server knows about a member whose updates are no longer
+	// gossiped.
+	srv1, srv2 := srvs[0], srvs[1]
+	srv1.Members.Add(Member{Name: ":3002", Address: ":3002"})
+	srv2.Members.Add(Member{Name: ":3003", Address: ":3003"})
+	srv1.Members.Updates = nil
+	srv2.Members.Updates = nil
+
+	if err := srv2.sendPushPull(serverAddr); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, _, ok := srv2.Members.State(":3002"); !ok || state != Alive {
+		t.Errorf("srv2: state = %v, %v; want = %v, %v", state, ok, Alive, true)
+	}
+
+	// The receiving side merges after answering.
+	deadline := time.Now().Add(time.Second)
+	for {
+		srv1.mu.Lock()
+		_, ok1 := srv1.Members.Members[":3003"]
+		_, ok2 := srv1.Members.Members[clientAddr]
+		srv1.mu.Unlock()
+
+		if ok1 && ok2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("srv1 did not merge the list of srv2")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLeave(t *testing.T) {
+	t.Parallel()
+
+	var (
+		serverAddr       = ":3000"
+		firstClientAddr  = ":3001"
+		secondClientAddr = ":3002"
+		interval         = 10
+		network          = NewMemNetwork()
+		logger           = log.New(ioutil.Discard, "", 0)
+	)
+
+	var servers []*Server
+	for _, addr := range []string{serverAddr, firstClientAddr, secondClientAddr} {
+		srv := NewServer(addr, interval, logger)
+		srv.Transport = newMemTransport(t, network, addr)
+		if err := srv.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer srv.Shutdown()
+
+		go func() {
+			if err := srv.Listen(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		if addr != serverAddr {
+			if err := srv.Join(serverAddr); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		servers = append(servers, srv)
+	}
+
+	if err := servers[2].Leave(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both remaining members acknowledged the departure, without
+	// suspecting the member first.
+	for _, srv := range servers[:2] {
+		srv.mu.Lock()
+		state, _, _ := srv.Members.State(secondClientAddr)
+		_, failed := srv.Members.Failed[secondClientAddr]
+		srv.mu.Unlock()
+
+		if state != Departed {
+			t.Errorf("%s: state = %v; want = %v", srv.BindAddr, state, Departed)
+		}
+		if failed {
+			t.Errorf("%s: %s was declared failed", srv.BindAddr, secondClientAddr)
+		}
+	}
+
+	// The departed member doesn't refute its own departure.
+	servers[2].mu.Lock()
+	state, _, _ := servers[2].Members.State(secondClientAddr)
+	servers[2].mu.Unlock()
+
+	if state != Departed {
+		t.Errorf("state = %v; want = %v", state, Departed)
+	}
+}
+
+func TestLeaveAlone(t *testing.T) {
+	t.Parallel()
+
+	var (
+		serverAddr = ":3000"
+		interval   = 10
+		network    = NewMemNetwork()
+		logger     = log.New(ioutil.Discard, "", 0)
+	)
+
+	srv := NewServer(serverAddr, interval, logger)
+	srv.Transport = newMemTransport(t, network, serverAddr)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Shutdown()
+
+	if err := srv.Leave(time.Second); err != nil {
+		t.Errorf("srv.Leave() = %v; want = %v", err, nil)
+	}
+}
+
+func newMemTransport(t *testing.T, network *MemNetwork, addr string) Transport {
+	tr, err := network.NewTransport(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
@@ -0,0 +1,51 @@
+package swim
+
+import (
+	"math"
+	"time"
+)
+
+// suspicion tracks a suspected member and the members that independently
+// suspect it.
+type suspicion struct {
+	start       time.Time
+	incarnation uint64
+
+	// from holds the addresses of the members that suspect the member,
+	// including the one that raised the suspicion.
+	from map[string]struct{}
+}
+
+func newSuspicion(incarnation uint64) *suspicion {
+	return &suspicion{
+		start:       time.Now(),
+		incarnation: incarnation,
+		from:        make(map[string]struct{}),
+	}
+}
+
+// confirm records that the member at address from suspects the member.
+func (s *suspicion) confirm(from string) {
+	if from != "" {
+		s.from[from] = struct{}{}
+	}
+}
+
+// confirmations returns the number of independent confirmations.
+func (s *suspicion) confirmations() int {
+	return max(len(s.from)-1, 0)
+}
+
+// timeout returns how long the member stays suspected. It starts at hi and
+// decreases logarithmically towards lo as k confirmations arrive.
+func (s *suspicion) timeout(lo, hi time.Duration, k int) time.Duration {
+	hi = max(hi, lo)
+	if k < 1 {
+		return lo
+	}
+
+	frac := math.Log(float64(s.confirmations())+1) / math.Log(float64(k)+1)
+	d := hi - time.Duration(frac*float64(hi-lo))
+
+	return max(d, lo)
+}
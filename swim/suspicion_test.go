@@ -0,0 +1,47 @@
+package swim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuspicion_Timeout(t *testing.T) {
+	var (
+		lo = 1 * time.Second
+		hi = 6 * time.Second
+	)
+
+	sus := newSuspicion(0)
+	sus.confirm("origin")
+
+	if got := sus.timeout(lo, hi, 3); got != hi {
+		t.Errorf("timeout() = %v; want = %v", got, hi)
+	}
+
+	// Confirming twice from the same member doesn't count.
+	sus.confirm("a")
+	sus.confirm("a")
+	if got := sus.confirmations(); got != 1 {
+		t.Errorf("confirmations() = %d; want = %d", got, 1)
+	}
+
+	prev := sus.timeout(lo, hi, 3)
+	if prev >= hi || prev <= lo {
+		t.Errorf("timeout() = %v; want between %v and %v", prev, lo, hi)
+	}
+
+	sus.confirm("b")
+	if got := sus.timeout(lo, hi, 3); got >= prev {
+		t.Errorf("timeout() = %v; want less than %v", got, prev)
+	}
+
+	sus.confirm("c")
+	sus.confirm("d")
+	if got := sus.timeout(lo, hi, 3); got != lo {
+		t.Errorf("timeout() = %v; want = %v", got, lo)
+	}
+
+	if got := newSuspicion(0).timeout(lo, hi, 0); got != lo {
+		t.Errorf("timeout() = %v; want = %v without expected confirmations", got, lo)
+	}
+}
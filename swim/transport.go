@@ -0,0 +1,36 @@
+package swim
+
+import (
+	"net"
+	"time"
+)
+
+// Transport carries messages between nodes. Packets are used for the
+// frequent probes and may be lost, while streams are used for the larger
+// and less frequent exchanges, such as joining a cluster.
+type Transport interface {
+	// LocalAddr returns the address other nodes reach this transport at.
+	LocalAddr() string
+
+	// WriteTo sends a packet to the given address. A nil error does not
+	// mean that the packet was delivered.
+	WriteTo(b []byte, addr string) error
+
+	// PacketCh returns the channel of received packets.
+	PacketCh() <-chan *Packet
+
+	// DialTimeout opens a stream to the given address.
+	DialTimeout(addr string, timeout time.Duration) (net.Conn, error)
+
+	// StreamCh returns the channel of streams opened by other nodes.
+	StreamCh() <-chan net.Conn
+
+	// Shutdown stops the transport from sending and receiving.
+	Shutdown() error
+}
+
+// Packet is a packet received by a Transport.
+type Packet struct {
+	Buf  []byte
+	From string
+}